@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/internal/budget"
+	"github.com/crissyfield/powerhouse/internal/junit"
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// CmdCheck defines the CLI sub-command 'check'.
+var CmdCheck = &cobra.Command{
+	Use:   "check [flags] recording...",
+	Short: "Check power budgets of saved recordings",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runCheck,
+}
+
+// Initialize CLI options.
+func init() {
+	// Check
+	addBudgetFlags(CmdCheck)
+}
+
+// addBudgetFlags adds the flags for power budget checks to a command.
+func addBudgetFlags(c *cobra.Command) {
+	c.Flags().StringSliceP("budget", "B", nil, "power budget as \"[phase:]metric<=max\", e.g. \"checkout:energy<=50\"")
+	c.Flags().String("junit", "", "write budget checks and phases as JUnit XML to this file")
+}
+
+// runCheck is called when the "check" command is used.
+func runCheck(_ *cobra.Command, args []string) {
+	budgets := parseBudgets()
+
+	// Check recordings
+	var suites []junit.TestSuite
+
+	passed := true
+
+	for _, path := range args {
+		rec, label := readRecording(path)
+
+		ts, ok := checkBudgets(label, rec, budgets)
+		suites = append(suites, ts)
+		passed = passed && ok
+	}
+
+	writeJUnit(suites...)
+
+	if !passed {
+		slog.Error("Power budget exceeded or run invalid")
+		os.Exit(exitBudgetExceeded) //nolint
+	}
+
+	slog.Info("Done")
+}
+
+// parseBudgets parses the power budgets given on the command line or in the config, and exits on error.
+func parseBudgets() []budget.Budget {
+	budgets, err := budget.ParseAll(viper.GetStringSlice("budget"))
+	if err != nil {
+		slog.Error("Unable to parse power budgets", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	return budgets
+}
+
+// checkBudgets checks the budgets against a recording, logs the results, and returns the test suite of the
+// recording. The returned flag is false if the test suite has failures, i.e. if a budget was exceeded, if a process
+// crashed during the recording, or if a phase contains no samples.
+func checkBudgets(name string, rec *powerhouse.Recording, budgets []budget.Budget) (junit.TestSuite, bool) {
+	summary := rec.Summary()
+	results := budget.Check(summary, budgets)
+
+	for _, r := range results {
+		if r.Passed() {
+			slog.Info("Budget met", slog.String("recording", name), slog.String("result", r.Message()))
+		} else {
+			slog.Warn("Budget exceeded", slog.String("recording", name), slog.String("result", r.Message()))
+		}
+	}
+
+	// Crashes invalidate the recording
+	for _, cr := range rec.Crashes {
+		slog.Warn("Run invalid, process crashed", slog.String("recording", name), slog.String("process", cr.Process),
+			slog.Time("time", cr.Time))
+	}
+
+	// So do phases without samples
+	for _, ps := range summary.Phases {
+		if ps.Samples == 0 {
+			slog.Warn("Run invalid, phase contains no samples", slog.String("recording", name),
+				slog.String("phase", ps.Label))
+		}
+	}
+
+	ts := junit.NewTestSuite(name, rec, results)
+
+	return ts, ts.Failures == 0
+}
+
+// writeJUnit writes the test suites to the JUnit XML file given on the command line, if any, and exits on error.
+func writeJUnit(suites ...junit.TestSuite) {
+	path := viper.GetString("junit")
+	if path == "" {
+		return
+	}
+
+	err := junit.WriteFile(path, suites...)
+	if err != nil {
+		slog.Error("Unable to write JUnit XML", slog.String("path", path), slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("JUnit XML written", slog.String("path", path))
+}
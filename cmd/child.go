@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/daemon"
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// child is a command that runs while measuring.
+type child struct {
+	cmd      *exec.Cmd
+	listener net.Listener
+	exited   chan error
+}
+
+// startChild starts the command given by args. It passes the UDID of the measured device and the URL of a marker
+// endpoint, that adds markers via the given function, as environment variables. Its output is streamed to ours.
+func startChild(args []string, dev *powerhouse.Device, mark daemon.MarkFunc) (*child, error) {
+	// Serve marker endpoint on loopback
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen for markers: %w", err)
+	}
+
+	hs := &http.Server{Handler: daemon.MarkerHandler(mark), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = hs.Serve(listener) }()
+
+	// Start command
+	cmd := exec.Command(args[0], args[1:]...) //nolint
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"POWERHOUSE_UDID="+dev.UDID,
+		"POWERHOUSE_MARKER_URL=http://"+listener.Addr().String()+"/v1/markers",
+	)
+
+	err = cmd.Start()
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("start command: %w", err)
+	}
+
+	// Wait for exit
+	c := &child{cmd: cmd, listener: listener, exited: make(chan error, 1)}
+
+	go func() {
+		c.exited <- cmd.Wait()
+		listener.Close()
+	}()
+
+	return c, nil
+}
+
+// Exited returns a channel that receives the result of the command once it exited.
+func (c *child) Exited() <-chan error {
+	return c.exited
+}
+
+// Interrupt asks the command to exit.
+func (c *child) Interrupt() {
+	_ = c.cmd.Process.Signal(os.Interrupt)
+}
+
+// Kill kills the command.
+func (c *child) Kill() {
+	_ = c.cmd.Process.Kill()
+}
+
+// exitCode returns the exit code to pass through for the result of a command.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+
+	switch {
+	case err == nil:
+		return 0
+
+	case errors.As(err, &exitErr) && (exitErr.ExitCode() > 0):
+		return exitErr.ExitCode()
+
+	default:
+		// Killed by a signal, or not waited for properly
+		return 1
+	}
+}
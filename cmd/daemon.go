@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/internal/daemon"
+	"github.com/crissyfield/powerhouse/internal/dashboard"
+	"github.com/crissyfield/powerhouse/internal/server"
+)
+
+// CmdDaemon defines the CLI sub-command 'daemon'.
+var CmdDaemon = &cobra.Command{
+	Use:   "daemon [flags]",
+	Short: "Serve an HTTP API to control measurements remotely",
+	Args:  cobra.NoArgs,
+	Run:   runDaemon,
+}
+
+// Initialize CLI options.
+func init() {
+	// Daemon
+	addServerFlags(CmdDaemon, "127.0.0.1:7070")
+}
+
+// runDaemon is called when the "daemon" command is used.
+func runDaemon(_ *cobra.Command, _ []string) {
+	// Create server
+	srv := daemon.New(newPowerhouse)
+
+	// Shut down on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Serve
+	slog.Info("Listening", slog.String("address", viper.GetString("listen")))
+
+	// The dashboard page is public, as browsers can't send the token along when opening it
+	cfg := serverConfig()
+	cfg.Public = dashboard.Public
+
+	err := server.Serve(ctx, viper.GetString("listen"), srv.Handler(), cfg)
+	if err != nil {
+		slog.Error("Unable to serve", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Stop running sessions
+	srv.Close()
+
+	slog.Info("Done")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// newPowerhouse creates a new powerhouse, configured from the global options.
+func newPowerhouse() (*powerhouse.Powerhouse, error) {
+	return powerhouse.New(powerhouse.Config{
+		USBMuxAddress: viper.GetString("usbmux-address"),
+		PairRecords:   viper.GetString("pair-records"),
+		Hosts:         viper.GetStringSlice("host"),
+		Browse:        viper.GetBool("browse"),
+	})
+}
+
+// selectDevice returns the connected device with the UDID given by the "udid" option, or the first connected
+// device if no UDID was given. The "usb" and "network" options restrict the allowed connection types.
+func selectDevice() (*powerhouse.Device, error) {
+	// Create powerhouse
+	ph, err := newPowerhouse()
+	if err != nil {
+		return nil, fmt.Errorf("create powerhouse: %w", err)
+	}
+
+	// Select device
+	return ph.Device(
+		viper.GetString("udid"),
+		viper.GetBool("usb"),
+		viper.GetBool("network"),
+	)
+}
+
+// selectDevices returns the connected devices with the UDIDs given by the "udid" option, all connected devices if the
+// "all" option is set, or the first connected device otherwise. The "usb" and "network" options restrict the allowed
+// connection types.
+func selectDevices() ([]*powerhouse.Device, error) {
+	// Create powerhouse
+	ph, err := newPowerhouse()
+	if err != nil {
+		return nil, fmt.Errorf("create powerhouse: %w", err)
+	}
+
+	// Read list of devices
+	devices, err := ph.Devices(
+		viper.GetBool("usb"),
+		viper.GetBool("network"),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("read list of devices: %w", err)
+	}
+
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("%w: no device connected", powerhouse.ErrDeviceNotFound)
+	}
+
+	// Select devices
+	udids := viper.GetStringSlice("udid")
+
+	switch {
+	case len(udids) > 0:
+		selected := make([]*powerhouse.Device, 0, len(udids))
+
+		for _, udid := range udids {
+			i := slices.IndexFunc(devices, func(dev *powerhouse.Device) bool { return dev.UDID == udid })
+			if i < 0 {
+				return nil, fmt.Errorf("%w: %s", powerhouse.ErrDeviceNotFound, udid)
+			}
+
+			selected = append(selected, devices[i])
+		}
+
+		return selected, nil
+
+	case viper.GetBool("all"):
+		return devices, nil
+
+	default:
+		return devices[:1], nil
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// Exit codes, by error class, so scripts can decide to retry, re-pair or give up. Measurements that wrap a command
+// pass its exit code through instead.
+const (
+	exitFailure            = 1 // Any other error
+	exitBudgetExceeded     = 2 // Power budget exceeded, or run invalid
+	exitDeviceNotFound     = 3 // Device not connected, retry later
+	exitNotPaired          = 4 // Host not paired with the device, pair again
+	exitPasswordProtected  = 5 // Device locked with a passcode, unlock it
+	exitServiceUnavailable = 6 // Service can't be started, e.g. developer disk image not mounted
+	exitConnectionLost     = 7 // Connection to the device broke down, retry
+	exitMalformed          = 8 // Unexpected data from the device
+)
+
+// errorExitCode returns the exit code for an error.
+func errorExitCode(err error) int {
+	switch powerhouse.Classify(err) {
+	case powerhouse.ErrorClassDeviceNotFound:
+		return exitDeviceNotFound
+
+	case powerhouse.ErrorClassNotPaired:
+		return exitNotPaired
+
+	case powerhouse.ErrorClassPasswordProtected:
+		return exitPasswordProtected
+
+	case powerhouse.ErrorClassServiceUnavailable:
+		return exitServiceUnavailable
+
+	case powerhouse.ErrorClassConnectionLost:
+		return exitConnectionLost
+
+	case powerhouse.ErrorClassMalformed:
+		return exitMalformed
+
+	case powerhouse.ErrorClassOther, "":
+		return exitFailure
+	}
+
+	return exitFailure
+}
@@ -7,8 +7,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-
-	"github.com/crissyfield/powerhouse/internal/powerhouse"
 )
 
 // CmdList defines the CLI sub-command 'list'.
@@ -29,10 +27,10 @@ func init() {
 // runList is called when the "test" command is used.
 func runList(_ *cobra.Command, _ []string) {
 	// Create powerhouse
-	ph, err := powerhouse.New()
+	ph, err := newPowerhouse()
 	if err != nil {
 		slog.Error("Unable to create powerhouse", slog.Any("error", err))
-		os.Exit(1) //nolint
+		os.Exit(errorExitCode(err)) //nolint
 	}
 
 	// Read list of devices
@@ -43,7 +41,7 @@ func runList(_ *cobra.Command, _ []string) {
 
 	if err != nil {
 		slog.Error("Unable to read list of devices", slog.Any("error", err))
-		os.Exit(1) //nolint
+		os.Exit(errorExitCode(err)) //nolint
 	}
 
 	// Dump
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// CmdLockdown defines the CLI sub-command 'lockdown'.
+var CmdLockdown = &cobra.Command{
+	Use:   "lockdown",
+	Short: "Read and write lockdown values of a device",
+	Args:  cobra.NoArgs,
+}
+
+// CmdLockdownGet defines the CLI sub-command 'lockdown get'.
+var CmdLockdownGet = &cobra.Command{
+	Use:   "get [flags] [domain [key]]",
+	Short: "Read lockdown values, e.g. of domain com.apple.mobile.battery",
+	Args:  cobra.MaximumNArgs(2),
+	Run:   runLockdownGet,
+}
+
+// CmdLockdownSet defines the CLI sub-command 'lockdown set'.
+var CmdLockdownSet = &cobra.Command{
+	Use:   "set [flags] domain key value",
+	Short: "Write a lockdown value, e.g. EnableWifiConnections of domain com.apple.mobile.wireless_lockdown",
+	Args:  cobra.ExactArgs(3),
+	Run:   runLockdownSet,
+}
+
+// Initialize CLI options.
+func init() {
+	// Lockdown
+	CmdLockdown.PersistentFlags().StringP("udid", "U", "", "UDID of the device (default first connected device)")
+	CmdLockdown.PersistentFlags().BoolP("usb", "u", true, "allow USB devices")
+	CmdLockdown.PersistentFlags().BoolP("network", "n", true, "allow network devices")
+
+	// Lockdown set
+	CmdLockdownSet.Flags().StringP("type", "t", "string", "type of the value (string, bool, int, or float)")
+
+	// Subcommands
+	CmdLockdown.AddCommand(CmdLockdownGet)
+	CmdLockdown.AddCommand(CmdLockdownSet)
+}
+
+// runLockdownGet is called when the "lockdown get" command is used.
+func runLockdownGet(_ *cobra.Command, args []string) {
+	// Parse arguments
+	var domain, key string
+
+	if len(args) > 0 {
+		domain = args[0]
+	}
+
+	if len(args) > 1 {
+		key = args[1]
+	}
+
+	// Select device
+	dev, err := selectDevice()
+	if err != nil {
+		slog.Error("Unable to select device", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Read value
+	value, err := dev.LockdownValue(domain, key)
+	if err != nil {
+		slog.Error("Unable to read lockdown value", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Dump
+	_ = json.NewEncoder(os.Stdout).Encode(value)
+}
+
+// runLockdownSet is called when the "lockdown set" command is used.
+func runLockdownSet(_ *cobra.Command, args []string) {
+	// Parse value
+	value, err := parseLockdownValue(args[2], viper.GetString("type"))
+	if err != nil {
+		slog.Error("Unable to parse value", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Select device
+	dev, err := selectDevice()
+	if err != nil {
+		slog.Error("Unable to select device", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Write value
+	err = dev.SetLockdownValue(args[0], args[1], value)
+	if err != nil {
+		slog.Error("Unable to write lockdown value", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done")
+}
+
+// parseLockdownValue converts the textual value s into a value of the given type.
+func parseLockdownValue(s string, typ string) (any, error) {
+	switch typ {
+	case "string":
+		return s, nil
+
+	case "bool":
+		return strconv.ParseBool(s)
+
+	case "int":
+		return strconv.ParseInt(s, 10, 64)
+
+	case "float":
+		return strconv.ParseFloat(s, 64)
+
+	default:
+		return nil, fmt.Errorf("unknown value type: %s", typ)
+	}
+}
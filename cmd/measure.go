@@ -1,24 +1,28 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
-	"github.com/crissyfield/powerhouse/internal/powerhouse"
+	"github.com/crissyfield/powerhouse/internal/junit"
+	"github.com/crissyfield/powerhouse/internal/tui"
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
 )
 
 // CmdMeasure defines the CLI sub-command 'list'.
 var CmdMeasure = &cobra.Command{
-	Use:   "measure [flags]",
+	Use:   "measure [flags] [-- command [args...]]",
 	Short: "...",
-	Args:  cobra.NoArgs,
+	Args:  measureArgs,
 	Run:   runMeasure,
 }
 
@@ -26,42 +30,122 @@ var CmdMeasure = &cobra.Command{
 func init() {
 	// Measure
 	CmdMeasure.Flags().DurationP("duration", "d", 10*time.Minute, "max duration of the measurement")
+	CmdMeasure.Flags().StringSliceP("udid", "U", nil, "UDIDs of the devices to measure (default first connected device)")
+	CmdMeasure.Flags().Bool("all", false, "measure all connected devices")
 	CmdMeasure.Flags().BoolP("usb", "u", true, "allow USB devices")
 	CmdMeasure.Flags().BoolP("network", "n", true, "allow network devices")
+	CmdMeasure.Flags().BoolP("tui", "t", false, "show a full-screen terminal view instead of JSON lines")
+	CmdMeasure.Flags().StringP("record", "r", "", "write the recording to this file (e.g. run.phrec)")
+	CmdMeasure.Flags().String("launch", "", "launch the app with this bundle ID before measuring")
+	CmdMeasure.Flags().Bool("kill", false, "kill the launched app at the end of the measurement")
+	CmdMeasure.Flags().StringSlice("app", nil, "record the version of these apps at start and end (bundle IDs)")
+	CmdMeasure.Flags().Bool("processes", false, "collect per-process CPU usage, wakeups and energy impact")
+	CmdMeasure.Flags().Bool("cpu", false, "collect the system-wide CPU load")
+	CmdMeasure.Flags().Bool("gpu", false, "collect the GPU utilization")
+	CmdMeasure.Flags().Bool("memory", false, "collect the system-wide memory usage")
+	CmdMeasure.Flags().Bool("traffic", false, "collect the network traffic of the device")
+	CmdMeasure.Flags().Bool("syslog", false, "record device log lines")
+	CmdMeasure.Flags().StringSlice("syslog-process", nil, "only record log lines of these processes")
+	CmdMeasure.Flags().StringSlice("syslog-subsystem", nil, "only record log lines of these subsystems or libraries")
+	CmdMeasure.Flags().Bool("crashes", false, "collect crash reports, and flag the run invalid if a process crashed")
+	CmdMeasure.Flags().StringSlice("crash-process", nil, "only collect crash reports of these processes")
+	CmdMeasure.Flags().String("pcap", "", "capture network packets to this file (e.g. out.pcap)")
+	CmdMeasure.Flags().Duration("screenshots", 0, "take a screenshot at this interval (e.g. 5s), stored with the recording")
+	CmdMeasure.Flags().Bool("notifications", false, "mark device notifications, e.g. lock, app install and backup")
+	CmdMeasure.Flags().StringSlice("notification", nil, "also mark device notifications with these names")
+	CmdMeasure.Flags().StringArray("log-marker", nil, "turn device log lines into markers, as \"[type:]regexp\"")
+	addBudgetFlags(CmdMeasure)
 }
 
-// runMeasure is called when the "test" command is used.
-func runMeasure(_ *cobra.Command, _ []string) {
-	// Create powerhouse
-	ph, err := powerhouse.New()
-	if err != nil {
-		slog.Error("Unable to create powerhouse", slog.Any("error", err))
-		os.Exit(1) //nolint
+// measureArgs only allows a command after "--".
+func measureArgs(c *cobra.Command, args []string) error {
+	if (len(args) > 0) && (c.ArgsLenAtDash() != 0) {
+		return errors.New("the command to run must follow \"--\"")
 	}
 
-	// Read list of devices
-	devices, err := ph.Devices(
-		viper.GetBool("usb"),
-		viper.GetBool("network"),
-	)
+	return nil
+}
 
-	if err != nil {
-		slog.Error("Unable to read list of devices", slog.Any("error", err))
+// runMeasure is called when the "test" command is used.
+func runMeasure(_ *cobra.Command, args []string) {
+	if (len(args) > 0) && viper.GetBool("tui") {
+		slog.Error("The terminal view can't be used while running a command")
 		os.Exit(1) //nolint
 	}
 
-	if len(devices) == 0 {
-		slog.Warn("No device connected. Exiting")
+	if (viper.GetDuration("screenshots") > 0) && (viper.GetString("record") == "") {
+		slog.Error("Screenshots are only kept with a recording file")
 		os.Exit(1) //nolint
 	}
 
-	// Start reporting metrics
-	ctx, cancel := context.WithCancel(context.Background())
+	// Parse power budgets and log marker rules before measuring
+	budgets := parseBudgets()
 
-	metrics, err := devices[0].ReportMetrics(ctx)
+	logMarkers, err := powerhouse.ParseLogMarkerRules(viper.GetStringSlice("log-marker"))
 	if err != nil {
-		slog.Error("Unable to start metrics", slog.Any("error", err))
-		os.Exit(1) //nolint
+		slog.Error("Unable to parse log marker rules", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Select devices
+	devices, err := selectDevices()
+	if err != nil {
+		slog.Error("Unable to select devices", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Start measuring all devices
+	opts := powerhouse.MetricsOptions{
+		Processes:   viper.GetBool("processes"),
+		CPU:         viper.GetBool("cpu"),
+		GPU:         viper.GetBool("gpu"),
+		Memory:      viper.GetBool("memory"),
+		Network:     viper.GetBool("traffic"),
+		LogMarkers:  logMarkers,
+		Screenshots: viper.GetDuration("screenshots"),
+	}
+
+	if viper.GetBool("syslog") {
+		opts.Syslog = &powerhouse.LogFilter{
+			Processes:  viper.GetStringSlice("syslog-process"),
+			Subsystems: viper.GetStringSlice("syslog-subsystem"),
+		}
+	}
+
+	if viper.GetBool("crashes") {
+		opts.Crashes = &powerhouse.CrashFilter{Processes: viper.GetStringSlice("crash-process")}
+	}
+
+	if viper.GetBool("notifications") {
+		opts.Notifications = append(opts.Notifications, powerhouse.DefaultNotifications...)
+	}
+
+	opts.Notifications = append(opts.Notifications, viper.GetStringSlice("notification")...)
+
+	// Metrics of all sessions are merged into a single channel, without dropping any
+	metrics := make(chan deviceMetrics)
+
+	sessions := make([]*powerhouse.Session, 0, len(devices))
+
+	for _, dev := range devices {
+		// Screenshots are taken right into the directory of the recording
+		if opts.Screenshots > 0 {
+			opts.ScreenshotDir = powerhouse.RecordingScreenshotDir(devicePath(viper.GetString("record"), dev, len(devices)))
+		}
+
+		pcapPath := devicePath(viper.GetString("pcap"), dev, len(devices))
+		sessions = append(sessions, startSession(dev, opts, pcapPath, metrics))
+	}
+
+	// Markers are added to all sessions
+	mark := func(typ powerhouse.MarkerType, label string) powerhouse.Marker {
+		var m powerhouse.Marker
+
+		for _, s := range sessions {
+			m = s.Mark(typ, label)
+		}
+
+		return m
 	}
 
 	// Create signal that fires on interrupt
@@ -71,36 +155,322 @@ func runMeasure(_ *cobra.Command, _ []string) {
 	// Create timer that fires an interrupt
 	expired := time.NewTimer(viper.GetDuration("duration"))
 
+	// Optionally, show terminal view
+	var view *tui.TUI
+	var keys <-chan byte
+	var redraw <-chan time.Time
+
+	if viper.GetBool("tui") {
+		view, err = tui.New(devices)
+		if err != nil {
+			slog.Error("Unable to create terminal view", slog.Any("error", err))
+			os.Exit(errorExitCode(err)) //nolint
+		}
+
+		keys = view.Keys()
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		redraw = ticker.C
+	}
+
+	// Optionally, run command
+	var ch *child
+	var exited <-chan error
+	var result error
+	var interrupted bool
+	var failed *deviceMetrics
+
+	if len(args) > 0 {
+		mark(powerhouse.MarkerTypeBegin, "command")
+
+		ch, err = startChild(args, devices[0], mark)
+		if err != nil {
+			slog.Error("Unable to run command", slog.Any("error", err))
+			os.Exit(errorExitCode(err)) //nolint
+		}
+
+		exited = ch.Exited()
+	}
+
 	// Event loop
 loop:
 	for {
 		select {
 		case <-stop:
+			// Interrupt command and wait for it, kill it if stop is requested again
+			if ch != nil {
+				if interrupted {
+					slog.Info("Stop requested again, killing command")
+					ch.Kill()
+				} else {
+					slog.Info("Stop requested, waiting for command to exit")
+					ch.Interrupt()
+				}
+
+				interrupted = true
+
+				continue
+			}
+
 			// Stop
 			slog.Info("Stop requested")
 			break loop
 
+		case result = <-exited:
+			// Command is done
+			mark(powerhouse.MarkerTypeEnd, "command")
+			slog.Info("Command exited", slog.Int("code", exitCode(result)))
+
+			break loop
+
+		case k := <-keys:
+			// Hotkeys
+			switch k {
+			case 'm':
+				m := view.Mark()
+				mark(m.Type, m.Label)
+
+			case 'p':
+				view.TogglePause()
+
+			case 'q', 3: // 3 is Ctrl-C in raw mode
+				slog.Info("Stop requested")
+				break loop
+			}
+
+		case <-redraw:
+			// Update elapsed time
+			view.Draw()
+
 		case <-expired.C:
+			// Ask command to exit, and wait for it
+			if ch != nil {
+				slog.Info("Time is up, waiting for command to exit")
+				ch.Interrupt()
+
+				interrupted = true
+
+				continue
+			}
+
 			// Stop
 			slog.Info("Time is up")
 			break loop
 
-		case m := <-metrics:
-			// Handling of potential errors
+		case dm := <-metrics:
+			m := dm.m
+
+			// Handling of potential errors. Everything recorded so far is still written
 			if m.Err != nil {
-				slog.Error("Unable to report error", slog.Any("error", m.Err))
-				os.Exit(1) //nolint
+				if ch != nil {
+					ch.Kill()
+				}
+
+				// The error ends the JSON output, too
+				if (view == nil) && (exited == nil) {
+					writeMetrics(dm, len(sessions))
+				}
+
+				failed = &dm
+
+				break loop
+			}
+
+			if m.Crash != nil {
+				slog.Warn("Process crashed", slog.String("udid", dm.dev.UDID), slog.String("process", m.Crash.Process),
+					slog.String("file", m.Crash.File))
 			}
 
 			// Report
-			_ = json.NewEncoder(os.Stdout).Encode(m)
+			switch {
+			case view != nil:
+				view.Update(dm.dev, m)
+
+			case exited == nil:
+				// Output of the command is streamed instead
+				writeMetrics(dm, len(sessions))
+			}
+		}
+	}
+
+	if view != nil {
+		view.Close()
+	}
+
+	if failed != nil {
+		slog.Error("Unable to report metrics",
+			slog.String("udid", failed.dev.UDID),
+			slog.Any("error", failed.m.Err),
+			slog.String("class", string(powerhouse.Classify(failed.m.Err))),
+		)
+	}
+
+	// Stop all sessions, discarding metrics that are still reported meanwhile
+	go func() {
+		for range metrics { //nolint
+		}
+	}()
+
+	for _, s := range sessions {
+		s.Stop()
+	}
+
+	close(metrics)
+
+	// Write recordings, and check power budgets and crashes
+	var suites []junit.TestSuite
+
+	passed := true
+
+	for i, s := range sessions {
+		dev := devices[i]
+		rec := s.Recording()
+
+		logStateChanges(dev, rec)
+
+		if path := devicePath(viper.GetString("record"), dev, len(sessions)); path != "" {
+			err = rec.WriteFile(path)
+			if err != nil {
+				slog.Error("Unable to write recording", slog.String("path", path), slog.Any("error", err))
+				os.Exit(errorExitCode(err)) //nolint
+			}
+
+			slog.Info("Recording written", slog.String("path", path))
+		}
+
+		if (len(budgets) > 0) || (len(rec.Crashes) > 0) || (viper.GetString("junit") != "") {
+			ts, ok := checkBudgets(dev.Name, rec, budgets)
+			suites = append(suites, ts)
+			passed = passed && ok
+		}
+	}
+
+	writeJUnit(suites...)
+
+	// Errors take precedence over the exit code of the command, which was killed
+	if failed != nil {
+		os.Exit(errorExitCode(failed.m.Err)) //nolint
+	}
+
+	// Pass exit code of command through, which takes precedence over exceeded budgets
+	if result != nil {
+		os.Exit(exitCode(result)) //nolint
+	}
+
+	if !passed {
+		slog.Error("Power budget exceeded or run invalid")
+		os.Exit(exitBudgetExceeded) //nolint
+	}
+}
+
+// writeMetrics writes metrics as JSON line to stdout. If several devices are measured, the metrics are wrapped
+// together with the UDID of their device.
+func writeMetrics(dm deviceMetrics, n int) {
+	if n == 1 {
+		_ = json.NewEncoder(os.Stdout).Encode(dm.m)
+		return
+	}
+
+	_ = json.NewEncoder(os.Stdout).Encode(struct {
+		UDID    string
+		Metrics *powerhouse.Metrics
+	}{UDID: dm.dev.UDID, Metrics: dm.m})
+}
+
+// deviceMetrics are metrics reported by a device.
+type deviceMetrics struct {
+	dev *powerhouse.Device
+	m   *powerhouse.Metrics
+}
+
+// startSession launches the app under test and starts a session on the device, which sends all metrics to the given
+// channel, and exits on error. If pcapPath is not empty, packets are captured to that file.
+func startSession(
+	dev *powerhouse.Device,
+	opts powerhouse.MetricsOptions,
+	pcapPath string,
+	metrics chan<- deviceMetrics,
+) *powerhouse.Session {
+	// Optionally, launch app under test
+	var launched *powerhouse.LaunchedApp
+
+	if bundleID := viper.GetString("launch"); bundleID != "" {
+		var err error
+
+		launched, err = dev.LaunchApp(bundleID)
+		if err != nil {
+			slog.Error("Unable to launch app", slog.String("udid", dev.UDID), slog.String("bundleID", bundleID),
+				slog.Any("error", err))
+
+			os.Exit(errorExitCode(err)) //nolint
 		}
+
+		slog.Info("App launched",
+			slog.String("udid", dev.UDID),
+			slog.String("bundleID", launched.BundleID),
+			slog.String("version", launched.Version),
+			slog.String("build", launched.Build),
+			slog.Int("pid", launched.PID),
+		)
 	}
 
-	// Canceling the context stops reporting
-	cancel()
+	// Optionally, capture packets
+	if pcapPath != "" {
+		f, err := os.Create(pcapPath)
+		if err != nil {
+			slog.Error("Unable to create packet capture file", slog.String("path", pcapPath), slog.Any("error", err))
+			os.Exit(errorExitCode(err)) //nolint
+		}
+
+		// The file is closed once the process exits
+		opts.Capture = f
+	}
+
+	// Start session
+	s, err := dev.StartSession(powerhouse.SessionOptions{
+		Metrics:     opts,
+		Apps:        viper.GetStringSlice("app"),
+		LaunchedApp: launched,
+		KillApp:     viper.GetBool("kill"),
+		OnMetrics: func(m *powerhouse.Metrics) {
+			metrics <- deviceMetrics{dev: dev, m: m}
+		},
+	})
+	if err != nil {
+		slog.Error("Unable to start session", slog.String("udid", dev.UDID), slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	return s
+}
 
-	for range metrics { //nolint
-		// Do something with old metrics
+// logStateChanges warns about changes of the device state during a recording, which may affect it.
+func logStateChanges(dev *powerhouse.Device, rec *powerhouse.Recording) {
+	if rec.Metadata.EndState == nil {
+		slog.Warn("Unable to take device state at the end", slog.String("udid", dev.UDID))
+		return
 	}
+
+	for _, c := range rec.Metadata.EndState.Diff(rec.Metadata.StartState) {
+		slog.Warn("Device state changed during measurement",
+			slog.String("udid", dev.UDID),
+			slog.String("name", c.Name),
+			slog.String("from", c.From),
+			slog.String("to", c.To),
+		)
+	}
+}
+
+// devicePath returns the path of an output file of a device. If several devices are measured, the UDID of the
+// device is added to the file name, e.g. "run-<UDID>.phrec" for "run.phrec".
+func devicePath(path string, dev *powerhouse.Device, n int) string {
+	if (path == "") || (n == 1) {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+
+	return strings.TrimSuffix(path, ext) + "-" + dev.UDID + ext
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// CmdPair defines the CLI sub-command 'pair'.
+var CmdPair = &cobra.Command{
+	Use:   "pair [flags]",
+	Short: "Pair with a device and save the pair record",
+	Args:  cobra.NoArgs,
+	Run:   runPair,
+}
+
+// CmdValidate defines the CLI sub-command 'validate'.
+var CmdValidate = &cobra.Command{
+	Use:   "validate [flags]",
+	Short: "Validate the pair record of a device",
+	Args:  cobra.NoArgs,
+	Run:   runValidate,
+}
+
+// CmdUnpair defines the CLI sub-command 'unpair'.
+var CmdUnpair = &cobra.Command{
+	Use:   "unpair [flags]",
+	Short: "Unpair a device and delete its pair record",
+	Args:  cobra.NoArgs,
+	Run:   runUnpair,
+}
+
+// Initialize CLI options.
+func init() {
+	// Pair
+	CmdPair.Flags().DurationP("timeout", "t", 2*time.Minute, "max duration to wait for the user to tap \"Trust\"")
+
+	// Pair, validate, unpair
+	for _, c := range []*cobra.Command{CmdPair, CmdValidate, CmdUnpair} {
+		c.Flags().StringP("udid", "U", "", "UDID of the device (default first connected device)")
+		c.Flags().BoolP("usb", "u", true, "allow USB devices")
+		c.Flags().BoolP("network", "n", true, "allow network devices")
+	}
+}
+
+// runPair is called when the "pair" command is used.
+func runPair(_ *cobra.Command, _ []string) {
+	// Select device
+	dev, err := selectDevice()
+	if err != nil {
+		slog.Error("Unable to select device", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Pair
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("timeout"))
+	defer cancel()
+
+	slog.Info("Unlock the device and tap \"Trust\" when asked to trust this computer", slog.String("udid", dev.UDID))
+
+	err = dev.Pair(ctx)
+	if err != nil {
+		slog.Error("Unable to pair with device", slog.Any("error", err))
+		logPairingGuidance(err)
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done")
+}
+
+// runValidate is called when the "validate" command is used.
+func runValidate(_ *cobra.Command, _ []string) {
+	// Select device
+	dev, err := selectDevice()
+	if err != nil {
+		slog.Error("Unable to select device", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Validate
+	err = dev.ValidatePair()
+	if err != nil {
+		slog.Error("Unable to validate pair record", slog.Any("error", err))
+		logPairingGuidance(err)
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done")
+}
+
+// runUnpair is called when the "unpair" command is used.
+func runUnpair(_ *cobra.Command, _ []string) {
+	// Select device
+	dev, err := selectDevice()
+	if err != nil {
+		slog.Error("Unable to select device", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Unpair
+	err = dev.Unpair()
+	if err != nil {
+		slog.Error("Unable to unpair device", slog.Any("error", err))
+		logPairingGuidance(err)
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done")
+}
+
+// logPairingGuidance logs instructions on how to recover from the given pairing error.
+func logPairingGuidance(err error) {
+	switch {
+	case errors.Is(err, powerhouse.ErrPasswordProtected):
+		slog.Info("The device is locked with a passcode. Unlock it, keep it unlocked, and try again")
+
+	case errors.Is(err, powerhouse.ErrPairingDialogResponsePending):
+		slog.Info("The pairing dialog on the device is still waiting for a response. Tap \"Trust\" and try again")
+
+	case errors.Is(err, context.DeadlineExceeded):
+		slog.Info("Nobody responded to the pairing dialog in time. Try again and tap \"Trust\" on the device")
+
+	case errors.Is(err, powerhouse.ErrUserDeniedPairing):
+		slog.Info("Pairing was denied on the device. Try again and tap \"Trust\" instead of \"Don't Trust\"")
+
+	case errors.Is(err, powerhouse.ErrNotPaired):
+		slog.Info("The device does not know this pair record, or there is none. Run \"powerhouse pair\" to pair again")
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/internal/plot"
+)
+
+// CmdPlot defines the CLI sub-command 'plot'.
+var CmdPlot = &cobra.Command{
+	Use:   "plot [flags] recording...",
+	Short: "Draw charts of one or more recordings as SVG or PNG",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runPlot,
+}
+
+// Initialize CLI options.
+func init() {
+	// Plot
+	CmdPlot.Flags().StringP("output", "o", "plot.svg", "output file, either .svg or .png")
+	CmdPlot.Flags().StringSliceP("metrics", "m", []string{"power", "current", "voltage", "capacity", "temperature",
+		"brightness"}, "metrics to draw, one panel each, also \"cpu\", \"gpu\", \"memory\" and \"network\" if collected")
+	CmdPlot.Flags().Int("width", 1200, "width of the chart (in px)")
+	CmdPlot.Flags().Int("height", 0, "height of the chart (in px), or 0 for 160 px per metric")
+}
+
+// runPlot is called when the "plot" command is used.
+func runPlot(_ *cobra.Command, args []string) {
+	// Select metrics
+	chart := &plot.Chart{Width: viper.GetInt("width"), Height: viper.GetInt("height")}
+
+	for _, name := range viper.GetStringSlice("metrics") {
+		m, err := plot.MetricByName(name)
+		if err != nil {
+			slog.Error("Unable to select metric", slog.Any("error", err))
+			os.Exit(errorExitCode(err)) //nolint
+		}
+
+		chart.Metrics = append(chart.Metrics, m)
+	}
+
+	if len(chart.Metrics) == 0 {
+		slog.Error("No metrics to draw")
+		os.Exit(1) //nolint
+	}
+
+	if chart.Height <= 0 {
+		chart.Height = 64 + 160*len(chart.Metrics)
+	}
+
+	// Read recordings
+	for _, path := range args {
+		rec, label := readRecording(path)
+		chart.Series = append(chart.Series, plot.Series{Label: label, Recording: rec})
+	}
+
+	// Draw
+	output := viper.GetString("output")
+
+	var buf bytes.Buffer
+	var err error
+
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".svg":
+		err = chart.WriteSVG(&buf)
+
+	case ".png":
+		err = chart.WritePNG(&buf)
+
+	default:
+		slog.Error("Unsupported output format, use .svg or .png", slog.String("output", output))
+		os.Exit(1) //nolint
+	}
+
+	if err != nil {
+		slog.Error("Unable to draw chart", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Write
+	err = os.WriteFile(output, buf.Bytes(), 0o644) //nolint
+	if err != nil {
+		slog.Error("Unable to write chart", slog.String("output", output), slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done", slog.String("output", output))
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// readRecording reads a recording file and returns it with a label derived from the file name. Exits on error.
+func readRecording(path string) (*powerhouse.Recording, string) {
+	rec, err := powerhouse.ReadRecordingFile(path)
+	if err != nil {
+		slog.Error("Unable to read recording", slog.String("path", path), slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	return rec, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/internal/report"
+)
+
+// CmdReport defines the CLI sub-command 'report'.
+var CmdReport = &cobra.Command{
+	Use:   "report [flags] recording...",
+	Short: "Generate a self-contained HTML report of one or more recordings",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runReport,
+}
+
+// Initialize CLI options.
+func init() {
+	// Report
+	CmdReport.Flags().StringP("output", "o", "report.html", "output file")
+	CmdReport.Flags().String("baseline", "", "recording to compare all recordings against")
+	CmdReport.Flags().String("title", "Powerhouse Report", "title of the report")
+}
+
+// runReport is called when the "report" command is used.
+func runReport(_ *cobra.Command, args []string) {
+	r := &report.Report{Title: viper.GetString("title")}
+
+	// Read recordings
+	for _, path := range args {
+		r.Inputs = append(r.Inputs, readReportInput(path))
+	}
+
+	if path := viper.GetString("baseline"); path != "" {
+		in := readReportInput(path)
+		r.Baseline = &in
+	}
+
+	// Render
+	var buf bytes.Buffer
+
+	err := r.Write(&buf)
+	if err != nil {
+		slog.Error("Unable to generate report", slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	// Write
+	output := viper.GetString("output")
+
+	err = os.WriteFile(output, buf.Bytes(), 0o644) //nolint
+	if err != nil {
+		slog.Error("Unable to write report", slog.String("output", output), slog.Any("error", err))
+		os.Exit(errorExitCode(err)) //nolint
+	}
+
+	slog.Info("Done", slog.String("output", output))
+}
+
+// readReportInput reads a recording, labeled by its file name.
+func readReportInput(path string) report.Input {
+	rec, label := readRecording(path)
+	return report.Input{Label: label, Recording: rec}
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/crissyfield/powerhouse/internal/server"
+)
+
+// addServerFlags adds the options of network listeners to the given command.
+func addServerFlags(c *cobra.Command, defaultAddress string) {
+	c.Flags().StringP("listen", "L", defaultAddress, "address to listen on")
+	c.Flags().String("tls-cert", "", "PEM encoded TLS certificate (enables TLS together with --tls-key)")
+	c.Flags().String("tls-key", "", "PEM encoded TLS key")
+	c.Flags().String("tls-client-ca", "", "PEM encoded CA bundle to verify client certificates with (mutual TLS)")
+	c.Flags().Bool("insecure", false, "allow listening on a non-loopback address without TLS (tokens in cleartext)")
+}
+
+// serverConfig returns the configuration of network listeners. Bearer tokens are only read from the configuration
+// file (key "tokens") or from the environment, to keep them out of process listings.
+func serverConfig() server.Config {
+	return server.Config{
+		CertFile:     viper.GetString("tls-cert"),
+		KeyFile:      viper.GetString("tls-key"),
+		ClientCAFile: viper.GetString("tls-client-ca"),
+		Tokens:       viper.GetStringSlice("tokens"),
+		Insecure:     viper.GetBool("insecure"),
+	}
+}
@@ -0,0 +1,213 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// metric is a statistic of a summary that can be limited by a budget.
+type metric struct {
+	// Unit the statistic is given in.
+	Unit string
+
+	// Recording returns the statistic of a whole recording.
+	Recording func(s *powerhouse.Summary) float64
+
+	// Phase returns the statistic of a phase, or is nil if the statistic isn't available for phases.
+	Phase func(ps *powerhouse.PhaseSummary) float64
+}
+
+// metrics lists all statistics that can be limited, by name.
+var metrics = map[string]metric{
+	"energy": {
+		Unit:      "mWh",
+		Recording: func(s *powerhouse.Summary) float64 { return s.Energy * 1000.0 },
+		Phase:     func(ps *powerhouse.PhaseSummary) float64 { return ps.Energy * 1000.0 },
+	},
+	"average-power": {
+		Unit:      "W",
+		Recording: func(s *powerhouse.Summary) float64 { return s.AveragePower },
+		Phase:     func(ps *powerhouse.PhaseSummary) float64 { return ps.AveragePower },
+	},
+	"peak-power": {
+		Unit:      "W",
+		Recording: func(s *powerhouse.Summary) float64 { return s.PeakPower },
+	},
+	"average-current": {
+		Unit:      "A",
+		Recording: func(s *powerhouse.Summary) float64 { return s.AverageCurrent },
+	},
+}
+
+// Budget limits a statistic of a recording, or of one of its phases.
+type Budget struct {
+	// Phase the budget applies to, or empty for the whole recording.
+	Phase string
+
+	// Metric is the name of the limited statistic: "energy" (in mWh), "average-power" (in W), "peak-power" (in W)
+	// or "average-current" (in A).
+	Metric string
+
+	// Max is the maximum allowed value.
+	Max float64
+}
+
+// Parse parses a budget of the form "[phase:]metric<=max", e.g. "average-power<=1.5" or "checkout:energy<=50".
+func Parse(s string) (Budget, error) {
+	// Split limit
+	lhs, rhs, ok := strings.Cut(s, "<=")
+	if !ok {
+		return Budget{}, fmt.Errorf("invalid budget %q: expected \"[phase:]metric<=max\"", s)
+	}
+
+	limit, err := strconv.ParseFloat(strings.TrimSpace(rhs), 64)
+	if err != nil {
+		return Budget{}, fmt.Errorf("invalid budget %q: parse max: %w", s, err)
+	}
+
+	// Split phase and metric
+	b := Budget{Metric: strings.TrimSpace(lhs), Max: limit}
+
+	if i := strings.LastIndex(lhs, ":"); i >= 0 {
+		b.Phase, b.Metric = strings.TrimSpace(lhs[:i]), strings.TrimSpace(lhs[i+1:])
+	}
+
+	// Validate metric
+	m, ok := metrics[b.Metric]
+	if !ok {
+		return Budget{}, fmt.Errorf("invalid budget %q: unknown metric %q (known: %s)", s, b.Metric, metricNames())
+	}
+
+	if (b.Phase != "") && (m.Phase == nil) {
+		return Budget{}, fmt.Errorf("invalid budget %q: metric %q isn't available for phases", s, b.Metric)
+	}
+
+	return b, nil
+}
+
+// ParseAll parses all budgets.
+func ParseAll(ss []string) ([]Budget, error) {
+	budgets := make([]Budget, 0, len(ss))
+
+	for _, s := range ss {
+		b, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+
+		budgets = append(budgets, b)
+	}
+
+	return budgets, nil
+}
+
+// String formats the budget as parsed by Parse.
+func (b Budget) String() string {
+	s := fmt.Sprintf("%s<=%g", b.Metric, b.Max)
+
+	if b.Phase != "" {
+		s = b.Phase + ":" + s
+	}
+
+	return s
+}
+
+// Unit returns the unit of the budget's metric.
+func (b Budget) Unit() string {
+	return metrics[b.Metric].Unit
+}
+
+// Result is the outcome of checking a budget.
+type Result struct {
+	// Budget that was checked.
+	Budget Budget
+
+	// Value that was measured, unless Missing is set.
+	Value float64
+
+	// Missing is true if the phase of the budget wasn't found in the recording.
+	Missing bool
+}
+
+// Passed returns true if the budget was met.
+func (r Result) Passed() bool {
+	return !r.Missing && (r.Value <= r.Budget.Max)
+}
+
+// Message describes the result.
+func (r Result) Message() string {
+	// Name of the checked statistic
+	name := r.Budget.Metric
+	if r.Budget.Phase != "" {
+		name = fmt.Sprintf("%s of phase %q", r.Budget.Metric, r.Budget.Phase)
+	}
+
+	switch {
+	case r.Missing:
+		return fmt.Sprintf("%s: phase not found in recording", name)
+
+	case r.Passed():
+		return fmt.Sprintf("%s %.3f %s is within budget %g %s", name, r.Value, r.Budget.Unit(), r.Budget.Max,
+			r.Budget.Unit())
+
+	default:
+		return fmt.Sprintf("%s %.3f %s exceeds budget %g %s", name, r.Value, r.Budget.Unit(), r.Budget.Max,
+			r.Budget.Unit())
+	}
+}
+
+// Check checks all budgets against the summary of a recording. Budgets for phases that occur more than once are
+// checked against the sum (energy) or the duration-weighted average (power) of all occurrences.
+func Check(s *powerhouse.Summary, budgets []Budget) []Result {
+	results := make([]Result, 0, len(budgets))
+
+	for _, b := range budgets {
+		m := metrics[b.Metric]
+		r := Result{Budget: b}
+
+		if b.Phase == "" {
+			// Whole recording
+			r.Value = m.Recording(s)
+		} else {
+			// Combine all occurrences of the phase
+			var combined powerhouse.PhaseSummary
+
+			found := false
+
+			for _, ps := range s.Phases {
+				if ps.Label == b.Phase {
+					combined.Energy += ps.Energy
+					combined.Duration += ps.Duration
+					found = true
+				}
+			}
+
+			if combined.Duration > 0 {
+				combined.AveragePower = combined.Energy * 3600.0 / combined.Duration
+			}
+
+			r.Value = m.Phase(&combined)
+			r.Missing = !found
+		}
+
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// metricNames returns the names of all metrics, sorted.
+func metricNames() string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
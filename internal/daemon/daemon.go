@@ -0,0 +1,589 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/crissyfield/powerhouse/internal/dashboard"
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// Server serves an HTTP API to control measurement sessions remotely.
+type Server struct {
+	// Function to create a powerhouse, used to read a fresh list of devices
+	newPowerhouse func() (*powerhouse.Powerhouse, error)
+
+	// Sessions by ID, guarded by mu
+	mu       sync.Mutex
+	sessions map[string]*powerhouse.Session
+}
+
+// SessionInfo describes a session.
+type SessionInfo struct {
+	ID      string
+	UDID    string
+	Start   time.Time
+	End     time.Time
+	Running bool
+
+	// Error that ended the session prematurely, and its class
+	Error      string
+	ErrorClass powerhouse.ErrorClass
+}
+
+// liveMessage is sent to WebSocket clients.
+type liveMessage struct {
+	// Either "sample", "error" or "end"
+	Type string
+
+	// Sample, for type "sample", or the error (as class and message), for type "error"
+	Sample *powerhouse.Metrics
+
+	// Energy drawn since the start of the session (in Wh)
+	Energy float64
+}
+
+// upgrader upgrades HTTP connections to WebSocket.
+var upgrader = websocket.Upgrader{}
+
+// New creates a new server, that uses the given function to access devices.
+func New(newPowerhouse func() (*powerhouse.Powerhouse, error)) *Server {
+	return &Server{
+		newPowerhouse: newPowerhouse,
+		sessions:      make(map[string]*powerhouse.Session),
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (srv *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/devices", srv.handleListDevices)
+	mux.HandleFunc("GET /v1/sessions", srv.handleListSessions)
+	mux.HandleFunc("POST /v1/sessions", srv.handleStartSession)
+	mux.HandleFunc("GET /v1/sessions/{id}", srv.handleGetSession)
+	mux.HandleFunc("DELETE /v1/sessions/{id}", srv.handleDeleteSession)
+	mux.HandleFunc("POST /v1/sessions/{id}/markers", srv.handleAddMarker)
+	mux.HandleFunc("POST /v1/sessions/{id}/stop", srv.handleStopSession)
+	mux.HandleFunc("GET /v1/sessions/{id}/recording", srv.handleGetRecording)
+	mux.HandleFunc("GET /v1/sessions/{id}/summary", srv.handleGetSummary)
+	mux.HandleFunc("GET /v1/sessions/{id}/screenshots/{file}", srv.handleGetScreenshot)
+	mux.HandleFunc("GET /v1/sessions/{id}/events", srv.handleEvents)
+	mux.HandleFunc("GET /v1/sessions/{id}/ws", srv.handleWebSocket)
+	mux.Handle("GET /", dashboard.Handler())
+
+	return mux
+}
+
+// Close stops all running sessions, and removes their screenshots.
+func (srv *Server) Close() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	for _, s := range srv.sessions {
+		s.Stop()
+		removeScreenshots(s.Recording().ScreenshotDir)
+	}
+}
+
+// removeScreenshots removes the screenshot directory of a session, if there is one.
+func removeScreenshots(dir string) {
+	if dir == "" {
+		return
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		slog.Warn("Unable to remove screenshots", slog.String("dir", dir), slog.Any("error", err))
+	}
+}
+
+// handleListDevices lists all connected devices.
+func (srv *Server) handleListDevices(w http.ResponseWriter, _ *http.Request) {
+	// Read list of devices
+	devices, err := srv.devices()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, devices)
+}
+
+// handleListSessions lists all sessions.
+func (srv *Server) handleListSessions(w http.ResponseWriter, _ *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	infos := make([]*SessionInfo, 0, len(srv.sessions))
+
+	for _, s := range srv.sessions {
+		infos = append(infos, sessionInfo(s))
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleStartSession starts a new session on the device given in the request body.
+func (srv *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
+	// Parse request
+	var req struct {
+		UDID      string
+		Duration  string
+		Processes bool
+		CPU       bool
+		GPU       bool
+		Memory    bool
+		Network   bool
+
+		Syslog           bool
+		SyslogProcesses  []string
+		SyslogSubsystems []string
+		LogMarkers       []string
+
+		Crashes        bool
+		CrashProcesses []string
+
+		ScreenshotInterval string
+
+		Apps []string
+
+		Notifications     bool
+		NotificationNames []string
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("parse request: %w", err))
+		return
+	}
+
+	opts := powerhouse.SessionOptions{
+		Apps: req.Apps,
+		Metrics: powerhouse.MetricsOptions{
+			Processes: req.Processes,
+			CPU:       req.CPU,
+			GPU:       req.GPU,
+			Memory:    req.Memory,
+			Network:   req.Network,
+		},
+	}
+
+	if req.Syslog {
+		opts.Metrics.Syslog = &powerhouse.LogFilter{Processes: req.SyslogProcesses, Subsystems: req.SyslogSubsystems}
+	}
+
+	if req.Crashes {
+		opts.Metrics.Crashes = &powerhouse.CrashFilter{Processes: req.CrashProcesses}
+	}
+
+	if req.Notifications {
+		opts.Metrics.Notifications = append(opts.Metrics.Notifications, powerhouse.DefaultNotifications...)
+	}
+
+	opts.Metrics.Notifications = append(opts.Metrics.Notifications, req.NotificationNames...)
+
+	logMarkers, err := powerhouse.ParseLogMarkerRules(req.LogMarkers)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	opts.Metrics.LogMarkers = logMarkers
+
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("parse duration: %w", err))
+			return
+		}
+
+		opts.Duration = d
+	}
+
+	if req.ScreenshotInterval != "" {
+		d, err := time.ParseDuration(req.ScreenshotInterval)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("parse screenshot interval: %w", err))
+			return
+		}
+
+		opts.Metrics.Screenshots = d
+
+		// Screenshots are kept until the session is removed
+		opts.Metrics.ScreenshotDir, err = os.MkdirTemp("", "powerhouse-screenshots-")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("create screenshot directory: %w", err))
+			return
+		}
+	}
+
+	// Find device
+	ph, err := srv.newPowerhouse()
+	if err != nil {
+		removeScreenshots(opts.Metrics.ScreenshotDir)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("create powerhouse: %w", err))
+		return
+	}
+
+	dev, err := ph.Device(req.UDID, true, true)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, powerhouse.ErrDeviceNotFound) {
+			status = http.StatusNotFound
+		}
+
+		removeScreenshots(opts.Metrics.ScreenshotDir)
+		writeError(w, status, err)
+		return
+	}
+
+	// Start session
+	s, err := dev.StartSession(opts)
+	if err != nil {
+		removeScreenshots(opts.Metrics.ScreenshotDir)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("start session: %w", err))
+		return
+	}
+
+	srv.mu.Lock()
+	srv.sessions[s.ID] = s
+	srv.mu.Unlock()
+
+	slog.Info("Session started", slog.String("id", s.ID), slog.String("udid", dev.UDID))
+
+	writeJSON(w, http.StatusCreated, sessionInfo(s))
+}
+
+// handleGetSession describes a session.
+func (srv *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sessionInfo(s))
+}
+
+// handleDeleteSession stops and removes a session.
+func (srv *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	s.Stop()
+
+	srv.mu.Lock()
+	delete(srv.sessions, s.ID)
+	srv.mu.Unlock()
+
+	removeScreenshots(s.Recording().ScreenshotDir)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddMarker adds the marker given in the request body to a session.
+func (srv *Server) handleAddMarker(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	addMarker(w, r, s.Mark)
+}
+
+// MarkFunc adds a marker of the given type and label, and returns it.
+type MarkFunc func(typ powerhouse.MarkerType, label string) powerhouse.Marker
+
+// MarkerHandler returns an HTTP handler that adds markers via the given function. It serves "POST /v1/markers" with
+// the same request format as the markers endpoint of a session.
+func MarkerHandler(mark MarkFunc) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/markers", func(w http.ResponseWriter, r *http.Request) {
+		addMarker(w, r, mark)
+	})
+
+	return mux
+}
+
+// addMarker parses a marker request and adds the marker via the given function.
+func addMarker(w http.ResponseWriter, r *http.Request, mark MarkFunc) {
+	// Parse request
+	var req struct {
+		Type  powerhouse.MarkerType
+		Label string
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("parse request: %w", err))
+		return
+	}
+
+	switch req.Type {
+	case powerhouse.MarkerTypeBegin, powerhouse.MarkerTypeEnd, powerhouse.MarkerTypeEvent:
+
+	case "":
+		req.Type = powerhouse.MarkerTypeEvent
+
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown marker type: %s", req.Type))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mark(req.Type, req.Label))
+}
+
+// handleStopSession stops a session, which keeps its recording available.
+func (srv *Server) handleStopSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	s.Stop()
+
+	slog.Info("Session stopped", slog.String("id", s.ID))
+
+	writeJSON(w, http.StatusOK, sessionInfo(s))
+}
+
+// handleGetRecording returns the recording of a session.
+func (srv *Server) handleGetRecording(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.Recording())
+}
+
+// handleGetScreenshot returns a screenshot of a session as PNG image.
+func (srv *Server) handleGetScreenshot(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	rec := s.Recording()
+
+	for i := range rec.Screenshots {
+		if rec.Screenshots[i].File != r.PathValue("file") {
+			continue
+		}
+
+		data, err := rec.ScreenshotData(&rec.Screenshots[i])
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(data)
+
+		return
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Errorf("screenshot %q not found", r.PathValue("file")))
+}
+
+// handleGetSummary returns the summary of a session.
+func (srv *Server) handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.Summary())
+}
+
+// handleEvents streams live samples of a session as Server-Sent Events, until the session is done or the client
+// disconnects.
+func (srv *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	// Subscribe
+	samples, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Event loop
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case m, ok := <-samples:
+			if !ok {
+				// Session is done
+				_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+				flusher.Flush()
+
+				return
+			}
+
+			data, err := json.Marshal(m)
+			if err != nil {
+				continue
+			}
+
+			event := "sample"
+			if m.Err != nil {
+				event = "error"
+			}
+
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleWebSocket streams live samples of a session, together with the energy drawn so far, via WebSocket. This is
+// used by the dashboard.
+func (srv *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s, ok := srv.session(w, r)
+	if !ok {
+		return
+	}
+
+	// Upgrade connection
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	// Subscribe
+	samples, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	// Read (and discard) messages, to notice when the client goes away
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Event loop
+	for {
+		select {
+		case <-closed:
+			return
+
+		case m, ok := <-samples:
+			if !ok {
+				// Session is done
+				_ = conn.WriteJSON(&liveMessage{Type: "end", Energy: s.Energy()})
+				return
+			}
+
+			typ := "sample"
+			if m.Err != nil {
+				typ = "error"
+			}
+
+			if err := conn.WriteJSON(&liveMessage{Type: typ, Sample: m, Energy: s.Energy()}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// devices reads a fresh list of all connected devices.
+func (srv *Server) devices() ([]*powerhouse.Device, error) {
+	// Create powerhouse
+	ph, err := srv.newPowerhouse()
+	if err != nil {
+		return nil, fmt.Errorf("create powerhouse: %w", err)
+	}
+
+	// Read list of devices
+	devices, err := ph.Devices(true, true)
+	if err != nil {
+		return nil, fmt.Errorf("read list of devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// session returns the session given by the "id" path value, or writes a "not found" error.
+func (srv *Server) session(w http.ResponseWriter, r *http.Request) (*powerhouse.Session, bool) {
+	srv.mu.Lock()
+	s, ok := srv.sessions[r.PathValue("id")]
+	srv.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session %q not found", r.PathValue("id")))
+		return nil, false
+	}
+
+	return s, true
+}
+
+// sessionInfo describes the given session.
+func sessionInfo(s *powerhouse.Session) *SessionInfo {
+	rec := s.Recording()
+
+	info := &SessionInfo{
+		ID:    s.ID,
+		UDID:  rec.Device.UDID,
+		Start: rec.Start,
+		End:   rec.End,
+	}
+
+	select {
+	case <-s.Done():
+	default:
+		info.Running = true
+	}
+
+	if err := s.Err(); err != nil {
+		info.Error = err.Error()
+		info.ErrorClass = powerhouse.Classify(err)
+	}
+
+	return info
+}
+
+// writeJSON writes v as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as JSON response with the given status code, together with its class.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string
+		Class powerhouse.ErrorClass
+	}{Error: err.Error(), Class: powerhouse.Classify(err)})
+}
@@ -0,0 +1,267 @@
+// Powerhouse dashboard. Talks to the daemon API of the same origin.
+"use strict";
+
+// Bearer token, passed as "#token=..." once and remembered afterwards
+const hashToken = new URLSearchParams(location.hash.slice(1)).get("token");
+if (hashToken) {
+  localStorage.setItem("powerhouse-token", hashToken);
+  history.replaceState(null, "", location.pathname);
+}
+const token = localStorage.getItem("powerhouse-token");
+
+// Sessions shown, by ID
+const sessions = new Map();
+
+// api calls the daemon API and returns the parsed JSON response.
+async function api(method, path, body) {
+  const headers = { "Content-Type": "application/json" };
+  if (token) {
+    headers["Authorization"] = "Bearer " + token;
+  }
+
+  const resp = await fetch(path, { method, headers, body: body ? JSON.stringify(body) : undefined });
+  if (resp.status === 204) {
+    return null;
+  }
+
+  const data = await resp.json();
+  if (!resp.ok) {
+    throw new Error(data.Error || resp.statusText);
+  }
+
+  return data;
+}
+
+// setStatus shows a status or error message.
+function setStatus(msg) {
+  document.getElementById("status").textContent = msg;
+}
+
+// refreshDevices reloads the list of devices.
+async function refreshDevices() {
+  const devices = await api("GET", "/v1/devices");
+  const tbody = document.querySelector("#devices tbody");
+
+  tbody.replaceChildren(...devices.map((dev) => {
+    const tr = document.createElement("tr");
+
+    for (const v of [dev.Name, dev.Type, dev.OSVersion, dev.ConnectionType, dev.UDID]) {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.append(td);
+    }
+
+    const button = document.createElement("button");
+    button.textContent = "Start session";
+    button.onclick = () => startSession(dev.UDID).catch((e) => setStatus(e.message));
+
+    const td = document.createElement("td");
+    td.append(button);
+    tr.append(td);
+
+    return tr;
+  }));
+}
+
+// startSession starts a new session on the device with the given UDID.
+async function startSession(udid) {
+  const duration = document.getElementById("duration").value;
+  const screenshots = document.getElementById("screenshots").value;
+  const apps = document.getElementById("apps").value.split(",").map((a) => a.trim()).filter((a) => a);
+  await api("POST", "/v1/sessions", { UDID: udid, Duration: duration, ScreenshotInterval: screenshots, Apps: apps });
+  await refreshSessions();
+}
+
+// refreshSessions reloads the list of sessions and connects to running ones.
+async function refreshSessions() {
+  const infos = await api("GET", "/v1/sessions");
+  infos.sort((a, b) => a.Start.localeCompare(b.Start));
+
+  for (const info of infos) {
+    let s = sessions.get(info.ID);
+    if (!s) {
+      s = await createSession(info);
+      sessions.set(info.ID, s);
+    }
+
+    updateSession(s, info);
+  }
+}
+
+// createSession creates the view of a session, fills it with the recording so far, and connects to live samples.
+async function createSession(info) {
+  const el = document.getElementById("session-template").content.firstElementChild.cloneNode(true);
+  document.getElementById("sessions").prepend(el);
+
+  const s = { info, el, samples: [], markers: [], screenshots: [], energy: 0, ws: null };
+
+  el.querySelector(".title").textContent = info.UDID;
+
+  const mark = (type) => api("POST", `/v1/sessions/${info.ID}/markers`, {
+    Type: type,
+    Label: el.querySelector(".label").value,
+  }).then((m) => {
+    s.markers.push(m);
+    draw(s);
+  }).catch((e) => setStatus(e.message));
+
+  el.querySelector(".begin").onclick = () => mark("begin");
+  el.querySelector(".end").onclick = () => mark("end");
+  el.querySelector(".event").onclick = () => mark("event");
+  el.querySelector(".stop").onclick = () => api("POST", `/v1/sessions/${info.ID}/stop`)
+    .then(refreshSessions)
+    .catch((e) => setStatus(e.message));
+
+  // Nearest screenshot when hovering over a chart
+  for (const canvas of el.querySelectorAll("canvas")) {
+    canvas.onmousemove = (ev) => showScreenshot(s, canvas, ev.offsetX);
+  }
+
+  // Recording so far
+  const rec = await api("GET", `/v1/sessions/${info.ID}/recording`);
+  s.samples = rec.Samples || [];
+  s.markers = rec.Markers || [];
+  s.screenshots = rec.Screenshots || [];
+  s.energy = (await api("GET", `/v1/sessions/${info.ID}/summary`)).Energy;
+
+  // Live samples
+  if (info.Running) {
+    const proto = location.protocol === "https:" ? "wss:" : "ws:";
+    const query = token ? "?access_token=" + encodeURIComponent(token) : "";
+
+    s.ws = new WebSocket(`${proto}//${location.host}/v1/sessions/${info.ID}/ws${query}`);
+    s.ws.onmessage = (ev) => {
+      const msg = JSON.parse(ev.data);
+      if (msg.Type === "error") {
+        setStatus(msg.Sample.Err.Message);
+      } else if (msg.Type === "sample" && msg.Sample.Marker) {
+        s.markers.push(msg.Sample.Marker);
+      } else if (msg.Type === "sample" && msg.Sample.Screenshot) {
+        s.screenshots.push(msg.Sample.Screenshot);
+      } else if (msg.Type === "sample") {
+        s.samples.push(msg.Sample);
+      }
+      s.energy = msg.Energy;
+      draw(s);
+      if (msg.Type === "end") {
+        refreshSessions();
+      }
+    };
+  }
+
+  draw(s);
+
+  return s;
+}
+
+// updateSession updates the state of a session.
+function updateSession(s, info) {
+  s.info = info;
+
+  const state = s.el.querySelector(".state");
+  state.textContent = info.Running ? "running" : (info.Error ? "failed: " + info.Error : "stopped");
+  state.classList.toggle("running", info.Running);
+
+  for (const button of s.el.querySelectorAll("button")) {
+    button.disabled = !info.Running;
+  }
+}
+
+// draw redraws energy and charts of a session.
+function draw(s) {
+  s.el.querySelector(".energy").textContent = (s.energy * 1000).toFixed(1) + " mWh";
+
+  const points = (fn) => s.samples
+    .filter((m) => m.Battery)
+    .map((m) => ({ t: Date.parse(m.Battery.Time), v: fn(m) }));
+
+  const markers = s.markers.map((m) => ({ t: Date.parse(m.Time), label: m.Label }));
+
+  drawChart(s.el.querySelector(".power"), points((m) => -m.Battery.Voltage * m.Battery.InstantAmperage), markers, "#bf4800");
+  drawChart(s.el.querySelector(".current"), points((m) => -m.Battery.InstantAmperage), markers, "#0071e3");
+  drawChart(s.el.querySelector(".brightness"), points((m) => m.Backlight ? m.Backlight.BrightnessValue : 0), markers, "#b8860b");
+}
+
+// showScreenshot shows the screenshot nearest to the time at the given position of a chart.
+function showScreenshot(s, canvas, offsetX) {
+  if (!canvas.chart || s.screenshots.length === 0) {
+    return;
+  }
+
+  const t = canvas.chart.t(offsetX * canvas.width / canvas.clientWidth);
+  const nearest = s.screenshots.reduce((a, b) =>
+    Math.abs(Date.parse(b.Time) - t) < Math.abs(Date.parse(a.Time) - t) ? b : a);
+
+  const query = token ? "?access_token=" + encodeURIComponent(token) : "";
+  const figure = s.el.querySelector(".screenshot");
+  const img = figure.querySelector("img");
+  const src = `/v1/sessions/${s.info.ID}/screenshots/${encodeURIComponent(nearest.File)}${query}`;
+
+  if (img.getAttribute("src") !== src) {
+    img.src = src;
+  }
+  figure.querySelector("figcaption").textContent =
+    "Screenshot at " + Math.round((Date.parse(nearest.Time) - Date.parse(s.info.Start)) / 1000) + " s";
+  figure.hidden = false;
+}
+
+// drawChart draws a simple line chart of the given points, with markers as vertical lines.
+function drawChart(canvas, points, markers, color) {
+  const ctx = canvas.getContext("2d");
+  const w = canvas.width, h = canvas.height, pad = 30;
+
+  ctx.clearRect(0, 0, w, h);
+  if (points.length === 0) {
+    return;
+  }
+
+  const t0 = points[0].t, t1 = Math.max(points[points.length - 1].t, t0 + 1);
+  let v0 = Math.min(0, ...points.map((p) => p.v)), v1 = Math.max(...points.map((p) => p.v));
+  if (v1 <= v0) {
+    v1 = v0 + 1;
+  }
+
+  const x = (t) => pad + (t - t0) / (t1 - t0) * (w - pad - 5);
+  canvas.chart = { t: (x) => t0 + (x - pad) / (w - pad - 5) * (t1 - t0) };
+  const y = (v) => h - pad + 10 - (v - v0) / (v1 - v0) * (h - pad - 5);
+
+  // Axes labels
+  ctx.fillStyle = "#6e6e73";
+  ctx.font = "10px sans-serif";
+  ctx.fillText(v1.toFixed(2), 2, y(v1) + 8);
+  ctx.fillText(v0.toFixed(2), 2, y(v0));
+  ctx.fillText(Math.round((t1 - t0) / 1000) + " s", w - 40, h - 4);
+
+  // Markers
+  ctx.strokeStyle = "#d2d2d7";
+  ctx.setLineDash([3, 3]);
+  for (const m of markers.filter((m) => m.t >= t0 && m.t <= t1)) {
+    ctx.beginPath();
+    ctx.moveTo(x(m.t), 5);
+    ctx.lineTo(x(m.t), h - pad + 10);
+    ctx.stroke();
+    ctx.fillText(m.label, x(m.t) + 2, 12);
+  }
+  ctx.setLineDash([]);
+
+  // Line
+  ctx.strokeStyle = color;
+  ctx.lineWidth = 1.5;
+  ctx.beginPath();
+  points.forEach((p, i) => (i === 0 ? ctx.moveTo(x(p.t), y(p.v)) : ctx.lineTo(x(p.t), y(p.v))));
+  ctx.stroke();
+}
+
+// Refresh periodically
+async function refresh() {
+  try {
+    await refreshDevices();
+    await refreshSessions();
+    setStatus("");
+  } catch (e) {
+    setStatus(e.message);
+  }
+}
+
+refresh();
+setInterval(refresh, 10000);
@@ -0,0 +1,42 @@
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// assets contains the static files of the dashboard.
+//
+//go:embed assets
+var assets embed.FS
+
+// Handler returns an HTTP handler serving the dashboard. The dashboard talks to the daemon API, so it must be served
+// from the same origin.
+func Handler() http.Handler {
+	sub, err := fs.Sub(assets, "assets")
+	if err != nil {
+		// Can't happen, the directory is embedded
+		panic(err)
+	}
+
+	return http.FileServer(http.FS(sub))
+}
+
+// Public returns true if the request is for a static file of the dashboard. These don't expose any data, so they can
+// be served without authentication, while the dashboard authenticates its requests to the daemon API.
+func Public(r *http.Request) bool {
+	if (r.Method != http.MethodGet) && (r.Method != http.MethodHead) {
+		return false
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" {
+		return true
+	}
+
+	info, err := fs.Stat(assets, "assets/"+name)
+
+	return (err == nil) && !info.IsDir()
+}
@@ -0,0 +1,133 @@
+package idevice
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+)
+
+// serviceConn is a connection to a service port of a device, that can optionally be upgraded to SSL.
+type serviceConn struct {
+	// Underlying connection
+	conn net.Conn
+
+	// SSL connection, if enabled
+	sslConn *tls.Conn
+
+	// Read and write timeout, or 0 for none
+	timeout time.Duration
+}
+
+// newServiceConn wraps the given connection.
+func newServiceConn(conn net.Conn) *serviceConn {
+	return &serviceConn{conn: conn, timeout: defaultTimeout}
+}
+
+// Write ...
+func (c *serviceConn) Write(data []byte) error {
+	// Set deadline
+	if err := c.RawConn().SetWriteDeadline(c.deadline()); err != nil {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+
+	// Write
+	if _, err := c.RawConn().Write(data); err != nil {
+		return fmt.Errorf("write: %w: %w", ErrConnectionLost, err)
+	}
+
+	return nil
+}
+
+// Read ...
+func (c *serviceConn) Read(length int) ([]byte, error) {
+	// Set deadline
+	if err := c.RawConn().SetReadDeadline(c.deadline()); err != nil {
+		return nil, fmt.Errorf("set read deadline: %w", err)
+	}
+
+	// Read
+	data := make([]byte, length)
+
+	if _, err := io.ReadFull(c.RawConn(), data); err != nil {
+		return nil, fmt.Errorf("read: %w: %w", ErrConnectionLost, err)
+	}
+
+	return data, nil
+}
+
+// Handshake upgrades the connection to SSL, using the root certificate of the pair record.
+func (c *serviceConn) Handshake(version []int, pairRecord *libimobiledevice.PairRecord) error {
+	// Load certificate
+	cert, err := tls.X509KeyPair(pairRecord.RootCertificate, pairRecord.RootPrivateKey)
+	if err != nil {
+		return fmt.Errorf("load root certificate: %w", err)
+	}
+
+	// Handshake
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true, //nolint
+		MinVersion:         tls.VersionTLS10,
+		MaxVersion:         tls.VersionTLS11,
+	}
+
+	if (len(version) > 0) && (version[0] > 10) {
+		config.MaxVersion = tls.VersionTLS13
+	}
+
+	sslConn := tls.Client(c.conn, config)
+
+	if err := sslConn.Handshake(); err != nil {
+		return fmt.Errorf("SSL handshake: %w", err)
+	}
+
+	c.sslConn = sslConn
+
+	return nil
+}
+
+// DismissSSL continues to use the plain connection after an SSL handshake.
+func (c *serviceConn) DismissSSL() error {
+	c.sslConn = nil
+	return nil
+}
+
+// Close ...
+func (c *serviceConn) Close() {
+	if c.sslConn != nil {
+		_ = c.sslConn.Close()
+	}
+
+	_ = c.conn.Close()
+}
+
+// RawConn returns the SSL connection if enabled, and the plain connection otherwise.
+func (c *serviceConn) RawConn() net.Conn {
+	if c.sslConn != nil {
+		return c.sslConn
+	}
+
+	return c.conn
+}
+
+// Timeout sets the read and write timeout, or disables it for 0.
+func (c *serviceConn) Timeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// deadline returns the deadline for the next read or write.
+func (c *serviceConn) deadline() time.Time {
+	if c.timeout <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(c.timeout)
+}
@@ -0,0 +1,186 @@
+package idevice
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+// afcModeReadOnly opens files for reading.
+const afcModeReadOnly = 1
+
+// afcReadChunk is the number of bytes read from a file at once.
+const afcReadChunk = 64 * 1024
+
+// CrashReportClient reads crash reports from a device via the Apple File Conduit.
+type CrashReportClient struct {
+	// Underlying AFC client
+	afc *libimobiledevice.AfcClient
+
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+}
+
+// CrashReportFile describes a crash report file on a device.
+type CrashReportFile struct {
+	// Path of the file, relative to the crash report directory.
+	Path string
+
+	// Size of the file (in bytes), and its last modification.
+	Size    int64
+	ModTime time.Time
+}
+
+// MoveCrashReports asks the device to move new crash reports to the directory that is served by the crash report
+// copy service, and waits until it is done.
+func (lds *LockdownSession) MoveCrashReports() error {
+	// Start service
+	conn, err := lds.StartService(libimobiledevice.CrashReportMoverServiceName)
+	if err != nil {
+		return fmt.Errorf("start service: %w", err)
+	}
+
+	defer conn.Close()
+
+	// The device pings once it is done
+	ping, err := conn.Read(4)
+	if err != nil {
+		return fmt.Errorf("receive ping: %w", err)
+	}
+
+	if string(ping) != "ping" {
+		return fmt.Errorf("unexpected reply: %q", ping)
+	}
+
+	return nil
+}
+
+// StartCrashReportCopyService starts the crash report copy service.
+func (lds *LockdownSession) StartCrashReportCopyService() (*CrashReportClient, error) {
+	conn, err := lds.StartService(libimobiledevice.CrashReportCopyMobileServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	return &CrashReportClient{afc: libimobiledevice.NewAfcClient(conn), conn: conn}, nil
+}
+
+// Close ...
+func (crc *CrashReportClient) Close() {
+	crc.conn.Close()
+}
+
+// List returns all crash report files, including those in subdirectories.
+func (crc *CrashReportClient) List() ([]CrashReportFile, error) {
+	var files []CrashReportFile
+
+	// Walk directories
+	dirs := []string{"."}
+
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		// Read directory
+		resp, err := crc.call(libimobiledevice.AfcOperationReadDir, nulTerminated(dir))
+		if err != nil {
+			return nil, fmt.Errorf("read directory %q: %w", dir, err)
+		}
+
+		for _, name := range resp.Strings() {
+			if (name == ".") || (name == "..") {
+				continue
+			}
+
+			p := path.Join(dir, name)
+
+			// Stat entry
+			resp, err := crc.call(libimobiledevice.AfcOperationGetFileInfo, nulTerminated(p))
+			if err != nil {
+				return nil, fmt.Errorf("stat %q: %w", p, err)
+			}
+
+			info := resp.Map()
+
+			if info["st_ifmt"] == "S_IFDIR" {
+				dirs = append(dirs, p)
+				continue
+			}
+
+			size, _ := strconv.ParseInt(info["st_size"], 10, 64)
+			mtime, _ := strconv.ParseInt(info["st_mtime"], 10, 64)
+
+			files = append(files, CrashReportFile{Path: p, Size: size, ModTime: time.Unix(0, mtime)})
+		}
+	}
+
+	return files, nil
+}
+
+// ReadFile returns the contents of a crash report file.
+func (crc *CrashReportClient) ReadFile(p string) ([]byte, error) {
+	// Open file
+	req := binary.LittleEndian.AppendUint64(nil, afcModeReadOnly)
+	req = append(req, nulTerminated(p)...)
+
+	resp, err := crc.call(libimobiledevice.AfcOperationFileOpen, req)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", p, err)
+	}
+
+	if (resp.Operation != libimobiledevice.AfcOperationFileOpenResult) || (len(resp.Data) < 8) {
+		return nil, fmt.Errorf("open %q: unexpected reply %d", p, resp.Operation)
+	}
+
+	fd := binary.LittleEndian.AppendUint64(nil, resp.Uint64())
+
+	// Read until the end
+	var data []byte
+
+	for {
+		resp, err := crc.call(libimobiledevice.AfcOperationFileRead, binary.LittleEndian.AppendUint64(fd, afcReadChunk))
+		if err != nil {
+			return nil, fmt.Errorf("read %q: %w", p, err)
+		}
+
+		if len(resp.Payload) == 0 {
+			break
+		}
+
+		data = append(data, resp.Payload...)
+	}
+
+	// Close file
+	if _, err := crc.call(libimobiledevice.AfcOperationFileClose, fd); err != nil {
+		return nil, fmt.Errorf("close %q: %w", p, err)
+	}
+
+	return data, nil
+}
+
+// call sends an AFC request and returns the reply, or an error if the device replied with an error status.
+func (crc *CrashReportClient) call(op uint64, data []byte) (*libimobiledevice.AfcMessage, error) {
+	if err := crc.afc.Send(op, data, nil); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	resp, err := crc.afc.Receive()
+	if err != nil {
+		return nil, fmt.Errorf("receive reply: %w", err)
+	}
+
+	if err := resp.Err(); err != nil {
+		return nil, fmt.Errorf("device: %w", err)
+	}
+
+	return resp, nil
+}
+
+// nulTerminated returns a string as NUL-terminated bytes.
+func nulTerminated(s string) []byte {
+	return append([]byte(s), 0)
+}
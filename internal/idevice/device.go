@@ -5,30 +5,51 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-
-	giDevice "github.com/electricbubble/gidevice"
-	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
 )
 
+// backend connects to devices and provides their pair records.
+type backend interface {
+	// ReadBUID reads the system BUID of the host.
+	ReadBUID() (string, error)
+
+	// connect opens a connection to the given port of the device.
+	connect(dev *Device, port int) (*serviceConn, error)
+
+	// pairRecordStore returns the store for pair records.
+	pairRecordStore() PairRecordStore
+}
+
 // Device ...
 type Device struct {
-	// Underlying device
-	dev giDevice.Device
+	// Unique device ID
+	udid string
+
+	// Either "USB" or "Network"
+	connectionType string
+
+	// Device ID assigned by usbmuxd (usbmuxd only)
+	deviceID int
+
+	// Network address of the device (direct network connections only)
+	address string
+
+	// Related backend
+	backend backend
 
 	// Function to return internal lockdown client
 	internalLockdownClientFn func() (*LockdownClient, error)
 
 	// Function to read pair record
-	readPairRecordFn func() (*giDevice.PairRecord, error)
+	readPairRecordFn func() (*PairRecord, error)
 
 	// Function to return iOS version
 	iOSVersionFn func() ([]int, error)
 }
 
 // newDevice ...
-func newDevice(device giDevice.Device) *Device {
+func newDevice(udid string, connectionType string, backend backend) *Device {
 	// Return new device
-	dev := &Device{dev: device}
+	dev := &Device{udid: udid, connectionType: connectionType, backend: backend}
 
 	dev.internalLockdownClientFn = dev.internalLockdownClientFnOnce()
 	dev.readPairRecordFn = dev.readPairRecordFnOnce()
@@ -40,7 +61,7 @@ func newDevice(device giDevice.Device) *Device {
 // ConnectionType ...
 func (dev *Device) ConnectionType() string {
 	// ...
-	return dev.dev.Properties().ConnectionType
+	return dev.connectionType
 }
 
 // Info ...
@@ -55,6 +76,39 @@ func (dev *Device) Info() (any, error) {
 	return ldc.Info()
 }
 
+// UDID returns the unique device ID.
+func (dev *Device) UDID() string {
+	return dev.udid
+}
+
+// connect opens a connection to the given port of the device.
+func (dev *Device) connect(port int) (*serviceConn, error) {
+	return dev.backend.connect(dev, port)
+}
+
+// SavePairRecord saves the pair record to the pair record store.
+func (dev *Device) SavePairRecord(pairRecord *PairRecord) error {
+	if err := dev.backend.pairRecordStore().SavePairRecord(dev.UDID(), pairRecord); err != nil {
+		return fmt.Errorf("save pair record: %w", err)
+	}
+
+	return nil
+}
+
+// ReadPairRecord reads the pair record from the pair record store.
+func (dev *Device) ReadPairRecord() (*PairRecord, error) {
+	return dev.readPairRecordFn()
+}
+
+// DeletePairRecord deletes the pair record from the pair record store.
+func (dev *Device) DeletePairRecord() error {
+	if err := dev.backend.pairRecordStore().DeletePairRecord(dev.UDID()); err != nil {
+		return fmt.Errorf("delete pair record: %w", err)
+	}
+
+	return nil
+}
+
 // IOSVersion ...
 func (dev *Device) IOSVersion() ([]int, error) {
 	return dev.iOSVersionFn()
@@ -84,16 +138,16 @@ func (dev *Device) internalLockdownClientFnOnce() func() (*LockdownClient, error
 }
 
 // pairRecordFnOnce ...
-func (dev *Device) readPairRecordFnOnce() func() (*giDevice.PairRecord, error) {
-	var pairRecord *libimobiledevice.PairRecord
+func (dev *Device) readPairRecordFnOnce() func() (*PairRecord, error) {
+	var pairRecord *PairRecord
 	var err error
 	var once sync.Once
 
 	// Return function that reads pair record once
-	return func() (*giDevice.PairRecord, error) {
+	return func() (*PairRecord, error) {
 		once.Do(func() {
 			// Read pair record
-			innerPairRecord, innerErr := dev.dev.ReadPairRecord()
+			innerPairRecord, innerErr := dev.backend.pairRecordStore().ReadPairRecord(dev.UDID())
 			if innerErr != nil {
 				err = fmt.Errorf("read pair record: %w", innerErr)
 				return
@@ -123,29 +177,15 @@ func (dev *Device) iOSVersionFnOnce() func() ([]int, error) {
 			}
 
 			// Get lockdown product version
-			var value libimobiledevice.LockdownValueResponse
-
-			innerErr = ldc.send(
-				&libimobiledevice.LockdownValueRequest{
-					LockdownBasicRequest: libimobiledevice.LockdownBasicRequest{
-						Label:           libimobiledevice.BundleID,
-						ProtocolVersion: libimobiledevice.ProtocolVersion,
-						Request:         libimobiledevice.RequestTypeGetValue,
-					},
-					Domain: "",
-					Key:    "ProductVersion",
-				},
-				&value,
-			)
-
+			value, innerErr := ldc.GetValue("", "ProductVersion")
 			if innerErr != nil {
 				err = fmt.Errorf("get lockdown product version: %w", innerErr)
 				return
 			}
 
-			productVersion, ok := value.Value.(string)
+			productVersion, ok := value.(string)
 			if !ok {
-				err = fmt.Errorf("unexpected product version: %v", value.Value)
+				err = fmt.Errorf("unexpected product version: %v", value)
 				return
 			}
 
@@ -20,8 +20,8 @@ func newDiagnosticRelayClient(drc *libimobiledevice.DiagnosticsRelayClient) *Dia
 }
 
 // Close ...
-func (*DiagnosticRelayClient) Close() {
-	// TODO
+func (drc *DiagnosticRelayClient) Close() {
+	drc.drc.InnerConn().Close()
 }
 
 // ReadIORegistry ...
@@ -92,7 +92,7 @@ func (drc *DiagnosticRelayClient) send(req any, resp any) error {
 
 	// Parse response packet body
 	if _, err := plist.Unmarshal(response, resp); err != nil {
-		return fmt.Errorf("unmarshal response packet: %w", err)
+		return fmt.Errorf("unmarshal response packet: %w: %w", ErrMalformed, err)
 	}
 
 	return nil
@@ -0,0 +1,545 @@
+package idevice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+const (
+	// dtxMagic starts every DTX message header.
+	dtxMagic = 0x1F3D5B79
+
+	// dtxHeaderLength and dtxPayloadHeaderLength are the lengths of the message and payload headers (in bytes).
+	dtxHeaderLength        = 32
+	dtxPayloadHeaderLength = 16
+
+	// dtxMaxLength limits the length of a message fragment (in bytes).
+	dtxMaxLength = 64 << 20
+
+	// dtxSubscriptionBuffer is the number of unsolicited messages buffered per channel.
+	dtxSubscriptionBuffer = 64
+)
+
+// errDTXClosed is returned for calls on a closed DTX connection.
+var errDTXClosed = errors.New("DTX connection closed")
+
+// dtxMessage is a message of the DTX protocol used by instruments.
+type dtxMessage struct {
+	Identifier        uint32
+	ConversationIndex uint32
+	ChannelCode       int32
+	ExpectsReply      bool
+
+	// Obj is the selector of a call, or the return value of a reply.
+	Obj any
+
+	// Aux contains the arguments of a call.
+	Aux []any
+}
+
+// dtxConn is a connection speaking the DTX protocol. Replies are matched to their calls, unsolicited messages are
+// passed to the subscriber of their channel.
+type dtxConn struct {
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+
+	// Serializes writes
+	writeMu sync.Mutex
+
+	// State, guarded by mu
+	mu            sync.Mutex
+	lastID        uint32
+	lastChannel   int32
+	replies       map[uint32]chan *dtxMessage
+	subscriptions map[int32]chan *dtxMessage
+	err           error
+
+	// Closed once receiving stopped
+	done chan struct{}
+}
+
+// newDTXConn wraps the given connection and starts receiving messages.
+func newDTXConn(conn libimobiledevice.InnerConn) *dtxConn {
+	// Messages arrive whenever the device sends them
+	conn.Timeout(0)
+
+	c := &dtxConn{
+		conn:          conn,
+		replies:       make(map[uint32]chan *dtxMessage),
+		subscriptions: make(map[int32]chan *dtxMessage),
+		done:          make(chan struct{}),
+	}
+
+	go c.receive()
+
+	return c
+}
+
+// Close ...
+func (c *dtxConn) Close() {
+	c.conn.Close()
+	<-c.done
+}
+
+// call sends a message with the given selector and arguments on a channel. If expectsReply is true, it waits for
+// the reply and returns it.
+func (c *dtxConn) call(channel int32, selector string, args []any, expectsReply bool) (*dtxMessage, error) {
+	// Encode message
+	sel, err := archive(selector)
+	if err != nil {
+		return nil, fmt.Errorf("encode selector: %w", err)
+	}
+
+	aux, err := encodeAux(args)
+	if err != nil {
+		return nil, fmt.Errorf("encode arguments: %w", err)
+	}
+
+	// Register for reply
+	c.mu.Lock()
+
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+
+	c.lastID++
+	id := c.lastID
+
+	var reply chan *dtxMessage
+
+	if expectsReply {
+		reply = make(chan *dtxMessage, 1)
+		c.replies[id] = reply
+	}
+
+	c.mu.Unlock()
+
+	// Send message
+	flags := uint32(0x2)
+	if expectsReply {
+		flags |= 0x1000
+	}
+
+	err = c.write(id, 0, channel, expectsReply, flags, aux, sel)
+	if err != nil {
+		c.forget(id)
+		return nil, err
+	}
+
+	if !expectsReply {
+		return nil, nil
+	}
+
+	// Wait for reply
+	timer := time.NewTimer(defaultTimeout)
+	defer timer.Stop()
+
+	select {
+	case m, ok := <-reply:
+		if !ok {
+			return nil, c.closeErr()
+		}
+
+		if e, ok := m.Obj.(*NSError); ok {
+			return nil, e
+		}
+
+		return m, nil
+
+	case <-timer.C:
+		c.forget(id)
+		return nil, fmt.Errorf("no reply to %s within %v", selector, defaultTimeout)
+	}
+}
+
+// requestChannel opens the channel with the given identifier and returns its code.
+func (c *dtxConn) requestChannel(identifier string) (int32, error) {
+	c.mu.Lock()
+	c.lastChannel++
+	code := c.lastChannel
+	c.mu.Unlock()
+
+	_, err := c.call(0, "_requestChannelWithCode:identifier:", []any{code, identifier}, true)
+	if err != nil {
+		return 0, fmt.Errorf("request channel %s: %w", identifier, err)
+	}
+
+	return code, nil
+}
+
+// subscribe returns a channel receiving the unsolicited messages of the given channel. Messages are dropped if the
+// subscriber falls behind. The channel is closed on unsubscribe, or once the connection is closed.
+func (c *dtxConn) subscribe(channel int32) <-chan *dtxMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sub := make(chan *dtxMessage, dtxSubscriptionBuffer)
+
+	if c.err != nil {
+		close(sub)
+	} else {
+		c.subscriptions[channel] = sub
+	}
+
+	return sub
+}
+
+// unsubscribe stops passing unsolicited messages of the given channel.
+func (c *dtxConn) unsubscribe(channel int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if sub, ok := c.subscriptions[channel]; ok {
+		close(sub)
+		delete(c.subscriptions, channel)
+	}
+}
+
+// forget stops waiting for the reply to a message.
+func (c *dtxConn) forget(id uint32) {
+	c.mu.Lock()
+	delete(c.replies, id)
+	c.mu.Unlock()
+}
+
+// closeErr returns the error that ended receiving.
+func (c *dtxConn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// receive reads and dispatches messages until the connection fails.
+func (c *dtxConn) receive() {
+	var err error
+
+	for {
+		var m *dtxMessage
+
+		m, err = c.read()
+		if err != nil {
+			break
+		}
+
+		// Acknowledge messages that expect a reply
+		if m.ExpectsReply && (m.ConversationIndex == 0) {
+			err = c.write(m.Identifier, 1, m.ChannelCode, false, 0, nil, nil)
+			if err != nil {
+				break
+			}
+		}
+
+		c.dispatch(m)
+	}
+
+	// Fail everything that is still waiting
+	c.mu.Lock()
+
+	c.err = fmt.Errorf("%w: %w", errDTXClosed, err)
+
+	for id, reply := range c.replies {
+		close(reply)
+		delete(c.replies, id)
+	}
+
+	for channel, sub := range c.subscriptions {
+		close(sub)
+		delete(c.subscriptions, channel)
+	}
+
+	c.mu.Unlock()
+
+	close(c.done)
+}
+
+// dispatch passes a message to whoever waits for it.
+func (c *dtxConn) dispatch(m *dtxMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Replies
+	if m.ConversationIndex > 0 {
+		if reply, ok := c.replies[m.Identifier]; ok {
+			reply <- m
+			delete(c.replies, m.Identifier)
+		}
+
+		return
+	}
+
+	// Unsolicited messages on channels we opened carry the negated channel code
+	channel := m.ChannelCode
+	if channel < 0 {
+		channel = -channel
+	}
+
+	if sub, ok := c.subscriptions[channel]; ok {
+		select {
+		case sub <- m:
+		default:
+			// Subscriber fell behind
+		}
+	}
+}
+
+// write writes a single-fragment message.
+func (c *dtxConn) write(id, conversation uint32, channel int32, expectsReply bool, flags uint32, aux, obj []byte) error {
+	var buf bytes.Buffer
+
+	// Header
+	var reply uint32
+	if expectsReply {
+		reply = 1
+	}
+
+	_ = binary.Write(&buf, binary.LittleEndian, struct {
+		Magic             uint32
+		HeaderLength      uint32
+		FragmentID        uint16
+		FragmentCount     uint16
+		Length            uint32
+		Identifier        uint32
+		ConversationIndex uint32
+		ChannelCode       int32
+		ExpectsReply      uint32
+	}{
+		dtxMagic, dtxHeaderLength, 0, 1, uint32(dtxPayloadHeaderLength + len(aux) + len(obj)), id, conversation,
+		channel, reply,
+	})
+
+	// Payload
+	_ = binary.Write(&buf, binary.LittleEndian, struct {
+		Flags       uint32
+		AuxLength   uint32
+		TotalLength uint64
+	}{
+		flags, uint32(len(aux)), uint64(len(aux) + len(obj)),
+	})
+
+	buf.Write(aux)
+	buf.Write(obj)
+
+	// Send
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	return nil
+}
+
+// read reads a message, joining its fragments.
+func (c *dtxConn) read() (*dtxMessage, error) {
+	var payload []byte
+	var m *dtxMessage
+
+	for {
+		// Header
+		raw, err := c.conn.Read(dtxHeaderLength)
+		if err != nil {
+			return nil, fmt.Errorf("read header: %w", err)
+		}
+
+		var hdr struct {
+			Magic             uint32
+			HeaderLength      uint32
+			FragmentID        uint16
+			FragmentCount     uint16
+			Length            uint32
+			Identifier        uint32
+			ConversationIndex uint32
+			ChannelCode       int32
+			ExpectsReply      uint32
+		}
+
+		_ = binary.Read(bytes.NewReader(raw), binary.LittleEndian, &hdr)
+
+		if hdr.Magic != dtxMagic {
+			return nil, fmt.Errorf("%w: bad magic %#x", ErrMalformed, hdr.Magic)
+		}
+
+		if hdr.Length > dtxMaxLength {
+			return nil, fmt.Errorf("%w: fragment too long: %d bytes", ErrMalformed, hdr.Length)
+		}
+
+		m = &dtxMessage{
+			Identifier:        hdr.Identifier,
+			ConversationIndex: hdr.ConversationIndex,
+			ChannelCode:       hdr.ChannelCode,
+			ExpectsReply:      hdr.ExpectsReply == 1,
+		}
+
+		// The first of several fragments only announces the message
+		if (hdr.FragmentID == 0) && (hdr.FragmentCount > 1) {
+			continue
+		}
+
+		// Body
+		data, err := c.conn.Read(int(hdr.Length))
+		if err != nil {
+			return nil, fmt.Errorf("read body: %w", err)
+		}
+
+		payload = append(payload, data...)
+
+		if hdr.FragmentID+1 >= hdr.FragmentCount {
+			break
+		}
+	}
+
+	// Payload header
+	if len(payload) < dtxPayloadHeaderLength {
+		return m, nil
+	}
+
+	flags := binary.LittleEndian.Uint32(payload[0:])
+	auxLength := uint64(binary.LittleEndian.Uint32(payload[4:]))
+	totalLength := binary.LittleEndian.Uint64(payload[8:])
+	body := payload[dtxPayloadHeaderLength:]
+
+	if compression := (flags & 0xff000) >> 12; compression != 0 {
+		return nil, fmt.Errorf("%w: unsupported compression %d", ErrMalformed, compression)
+	}
+
+	if (auxLength > totalLength) || (totalLength > uint64(len(body))) {
+		return nil, fmt.Errorf(
+			"%w: invalid payload lengths %d/%d of %d bytes", ErrMalformed, auxLength, totalLength, len(body),
+		)
+	}
+
+	// Arguments
+	if auxLength > 0 {
+		aux, err := decodeAux(body[:auxLength])
+		if err != nil {
+			return nil, fmt.Errorf("decode arguments: %w: %w", ErrMalformed, err)
+		}
+
+		m.Aux = aux
+	}
+
+	// Selector or return value
+	if totalLength > auxLength {
+		obj, err := unarchive(body[auxLength:totalLength])
+		if err != nil {
+			return nil, fmt.Errorf("decode object: %w: %w", ErrMalformed, err)
+		}
+
+		m.Obj = obj
+	}
+
+	return m, nil
+}
+
+// encodeAux encodes the arguments of a message. Values of type int32 and int64 are passed as primitives, everything
+// else is archived.
+func encodeAux(args []any) ([]byte, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+
+	for _, arg := range args {
+		// Every argument is preceded by an empty dictionary key
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(10))
+
+		switch v := arg.(type) {
+		case int32:
+			_ = binary.Write(&buf, binary.LittleEndian, []uint32{3, uint32(v)})
+
+		case int64:
+			_ = binary.Write(&buf, binary.LittleEndian, uint32(4))
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+
+		default:
+			data, err := archive(v)
+			if err != nil {
+				return nil, err
+			}
+
+			_ = binary.Write(&buf, binary.LittleEndian, []uint32{2, uint32(len(data))})
+			buf.Write(data)
+		}
+	}
+
+	// Prepend header
+	hdr := make([]byte, 16)
+	binary.LittleEndian.PutUint64(hdr[0:], 0x1f0)
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(buf.Len()))
+
+	return append(hdr, buf.Bytes()...), nil
+}
+
+// decodeAux decodes the arguments of a message.
+func decodeAux(data []byte) ([]any, error) {
+	if len(data) < 16 {
+		return nil, fmt.Errorf("arguments too short: %d bytes", len(data))
+	}
+
+	r := bytes.NewReader(data[16:])
+
+	var args []any
+
+	for r.Len() > 0 {
+		var typ uint32
+		if err := binary.Read(r, binary.LittleEndian, &typ); err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case 10:
+			// Dictionary key, followed by the value
+
+		case 2:
+			var length uint32
+			if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+				return nil, err
+			}
+
+			if int(length) > r.Len() {
+				return nil, fmt.Errorf("argument too long: %d bytes", length)
+			}
+
+			obj := make([]byte, length)
+			_, _ = r.Read(obj)
+
+			v, err := unarchive(obj)
+			if err != nil {
+				return nil, err
+			}
+
+			args = append(args, v)
+
+		case 3, 5:
+			var v int32
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return nil, err
+			}
+
+			args = append(args, v)
+
+		case 4, 6:
+			var v int64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return nil, err
+			}
+
+			args = append(args, v)
+
+		default:
+			return nil, fmt.Errorf("unknown argument type %d", typ)
+		}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,60 @@
+package idevice
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDeviceNotFound is returned if a device isn't connected.
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrNotPaired is returned if the host isn't paired with a device. ErrPairRecordNotFound and ErrInvalidHostID
+	// wrap it.
+	ErrNotPaired = errors.New("device not paired")
+
+	// ErrServiceUnavailable is returned if a service of a device can't be started, e.g. because the developer disk
+	// image isn't mounted.
+	ErrServiceUnavailable = errors.New("service unavailable")
+
+	// ErrConnectionLost is returned if reading from or writing to a device fails, e.g. because it was disconnected.
+	ErrConnectionLost = errors.New("connection lost")
+
+	// ErrMalformed is returned if a message of a device can't be decoded.
+	ErrMalformed = errors.New("malformed data")
+
+	// ErrPasswordProtected is returned if the device is locked with a passcode and must be unlocked first.
+	ErrPasswordProtected = errors.New("device is password protected")
+
+	// ErrPairingDialogResponsePending is returned if the user has not yet responded to the pairing dialog.
+	ErrPairingDialogResponsePending = errors.New("pairing dialog response pending")
+
+	// ErrUserDeniedPairing is returned if the user denied the pairing request on the device.
+	ErrUserDeniedPairing = errors.New("user denied pairing")
+
+	// ErrPairRecordNotFound is returned if no pair record could be found for a device.
+	ErrPairRecordNotFound = fmt.Errorf("pair record not found: %w", ErrNotPaired)
+
+	// ErrInvalidHostID is returned if the device does not know the host ID of the pair record.
+	ErrInvalidHostID = fmt.Errorf("invalid host ID: %w", ErrNotPaired)
+)
+
+// lockdownError maps the error string of a lockdown response to an error.
+func lockdownError(s string) error {
+	switch s {
+	case "PasswordProtected", "DeviceLocked":
+		return ErrPasswordProtected
+
+	case "PairingDialogResponsePending":
+		return ErrPairingDialogResponsePending
+
+	case "UserDeniedPairing":
+		return ErrUserDeniedPairing
+
+	case "InvalidHostID":
+		return ErrInvalidHostID
+
+	default:
+		return errors.New(s)
+	}
+}
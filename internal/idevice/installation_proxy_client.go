@@ -0,0 +1,84 @@
+package idevice
+
+import (
+	"fmt"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+// InstallationProxyClient talks to the installation proxy service of a device.
+type InstallationProxyClient struct {
+	// Underlying installation proxy client
+	ipc *libimobiledevice.InstallationProxyClient
+
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+}
+
+// StartInstallationProxyService starts the installation proxy service.
+func (lds *LockdownSession) StartInstallationProxyService() (*InstallationProxyClient, error) {
+	// Start service
+	conn, err := lds.StartService(libimobiledevice.InstallationProxyServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	// Create installation proxy client
+	ipc := libimobiledevice.NewInstallationProxyClient(conn)
+
+	return &InstallationProxyClient{ipc: ipc, conn: conn}, nil
+}
+
+// Close ...
+func (ipc *InstallationProxyClient) Close() {
+	ipc.conn.Close()
+}
+
+// Lookup returns the given attributes (or all attributes, if none are given) of the installed apps with the given
+// bundle IDs, by bundle ID. Apps that aren't installed are missing from the result.
+func (ipc *InstallationProxyClient) Lookup(bundleIDs []string, attributes []string) (map[string]any, error) {
+	// Create request
+	pkt, err := ipc.ipc.NewXmlPacket(ipc.ipc.NewBasicRequest(
+		libimobiledevice.CommandTypeLookup,
+		&libimobiledevice.InstallationProxyOption{
+			ApplicationType:  libimobiledevice.ApplicationTypeAny,
+			BundleIDs:        bundleIDs,
+			ReturnAttributes: attributes,
+		},
+	))
+
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Send request
+	err = ipc.ipc.SendPacket(pkt)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	// Receive response
+	res, err := ipc.ipc.ReceivePacket()
+	if err != nil {
+		return nil, fmt.Errorf("receive response: %w", err)
+	}
+
+	var lookup libimobiledevice.InstallationProxyLookupResponse
+
+	err = res.Unmarshal(&lookup)
+	if err != nil {
+		return nil, fmt.Errorf("parse response: %w: %w", ErrMalformed, err)
+	}
+
+	if lookup.Status != "Complete" {
+		return nil, fmt.Errorf("lookup failed with status %q", lookup.Status)
+	}
+
+	// Result by bundle ID
+	result, ok := lookup.LookupResult.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected lookup result: %T", lookup.LookupResult)
+	}
+
+	return result, nil
+}
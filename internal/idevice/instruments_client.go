@@ -0,0 +1,237 @@
+package idevice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+const (
+	// processControlChannel is the instruments channel to launch and kill processes.
+	processControlChannel = "com.apple.instruments.server.services.processcontrol"
+
+	// sysmontapChannel is the instruments channel streaming system and process statistics.
+	sysmontapChannel = "com.apple.instruments.server.services.sysmontap"
+
+	// graphicsChannel is the instruments channel streaming GPU statistics.
+	graphicsChannel = "com.apple.instruments.server.services.graphics.opengl"
+)
+
+// InstrumentsClient talks to the instruments service of a device, which requires the developer disk image to be
+// mounted.
+type InstrumentsClient struct {
+	// Underlying DTX connection
+	dc *dtxConn
+}
+
+// StartInstrumentsService starts the instruments service, using the secure proxy on iOS 14 and later.
+func (lds *LockdownSession) StartInstrumentsService() (*InstrumentsClient, error) {
+	// Get iOS version
+	ver, err := lds.ldc.dev.iOSVersionFn()
+	if err != nil {
+		return nil, fmt.Errorf("get iOS version: %w", err)
+	}
+
+	service := libimobiledevice.InstrumentsServiceName
+	if (len(ver) > 0) && (ver[0] >= 14) {
+		service = libimobiledevice.InstrumentsSecureProxyServiceName
+	}
+
+	// Start service
+	conn, err := lds.StartService(service)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	// The plain service only uses SSL for the handshake
+	if service == libimobiledevice.InstrumentsServiceName {
+		_ = conn.DismissSSL()
+	}
+
+	// Exchange capabilities, the device answers with its own
+	dc := newDTXConn(conn)
+	caps := dc.subscribe(0)
+
+	_, err = dc.call(0, "_notifyOfPublishedCapabilities:", []any{map[string]any{
+		"com.apple.private.DTXBlockCompression": uint64(0),
+		"com.apple.private.DTXConnection":       uint64(1),
+	}}, false)
+
+	if err != nil {
+		dc.Close()
+		return nil, fmt.Errorf("notify of published capabilities: %w", err)
+	}
+
+	timer := time.NewTimer(defaultTimeout)
+	defer timer.Stop()
+
+	select {
+	case _, ok := <-caps:
+		if !ok {
+			dc.Close()
+			return nil, fmt.Errorf("receive published capabilities: %w", dc.closeErr())
+		}
+
+	case <-timer.C:
+		dc.Close()
+		return nil, fmt.Errorf("no published capabilities within %v", defaultTimeout)
+	}
+
+	dc.unsubscribe(0)
+
+	return &InstrumentsClient{dc: dc}, nil
+}
+
+// Close ...
+func (ic *InstrumentsClient) Close() {
+	ic.dc.Close()
+}
+
+// LaunchApp launches the app with the given bundle ID and returns its PID. If killExisting is true, a running
+// instance of the app is killed first, otherwise it is brought to the foreground.
+func (ic *InstrumentsClient) LaunchApp(bundleID string, killExisting bool) (int, error) {
+	// Request channel
+	channel, err := ic.dc.requestChannel(processControlChannel)
+	if err != nil {
+		return 0, fmt.Errorf("request process control channel: %w", err)
+	}
+
+	// Arguments: device path, bundle ID, environment, arguments, options
+	options := map[string]any{
+		"StartSuspendedKey": uint64(0),
+		"KillExisting":      uint64(0),
+	}
+
+	if killExisting {
+		options["KillExisting"] = uint64(1)
+	}
+
+	// Launch
+	res, err := ic.dc.call(
+		channel,
+		"launchSuspendedProcessWithDevicePath:bundleIdentifier:environment:arguments:options:",
+		[]any{"", bundleID, map[string]any{}, []any{}, options},
+		true,
+	)
+
+	if err != nil {
+		return 0, fmt.Errorf("invoke launch: %w", err)
+	}
+
+	pid, ok := res.Obj.(uint64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected launch result: %T", res.Obj)
+	}
+
+	return int(pid), nil
+}
+
+// KillProcess kills the process with the given PID.
+func (ic *InstrumentsClient) KillProcess(pid int) error {
+	// Request channel
+	channel, err := ic.dc.requestChannel(processControlChannel)
+	if err != nil {
+		return fmt.Errorf("request process control channel: %w", err)
+	}
+
+	// Kill
+	_, err = ic.dc.call(channel, "killPid:", []any{uint64(pid)}, false)
+	if err != nil {
+		return fmt.Errorf("invoke kill: %w", err)
+	}
+
+	return nil
+}
+
+// SysmontapConfig configures the statistics streamed by sysmontap.
+type SysmontapConfig struct {
+	// Interval between samples.
+	Interval time.Duration
+
+	// Attributes to report for each process, and for the whole system.
+	ProcessAttributes []string
+	SystemAttributes  []string
+}
+
+// StartSysmontap starts streaming statistics and returns a channel receiving the samples. Process attributes are
+// reported under "Processes" as lists in the configured order, keyed by PID. The channel must be drained until it is
+// closed, which happens once the client is closed.
+func (ic *InstrumentsClient) StartSysmontap(config SysmontapConfig) (<-chan map[string]any, error) {
+	// Request channel
+	channel, err := ic.dc.requestChannel(sysmontapChannel)
+	if err != nil {
+		return nil, fmt.Errorf("request sysmontap channel: %w", err)
+	}
+
+	sub := ic.dc.subscribe(channel)
+
+	// Configure
+	_, err = ic.dc.call(channel, "setConfig:", []any{map[string]any{
+		"ur":             uint64(config.Interval.Milliseconds()),
+		"bm":             uint64(0),
+		"cpuUsage":       true,
+		"sampleInterval": uint64(config.Interval.Nanoseconds()),
+		"procAttrs":      config.ProcessAttributes,
+		"sysAttrs":       config.SystemAttributes,
+	}}, true)
+
+	if err != nil {
+		ic.dc.unsubscribe(channel)
+		return nil, fmt.Errorf("invoke set config: %w", err)
+	}
+
+	// Start
+	_, err = ic.dc.call(channel, "start", nil, true)
+	if err != nil {
+		ic.dc.unsubscribe(channel)
+		return nil, fmt.Errorf("invoke start: %w", err)
+	}
+
+	return stream(sub), nil
+}
+
+// StartGraphics starts streaming GPU statistics and returns a channel receiving the samples, e.g. with the key
+// "Device Utilization %". The channel must be drained until it is closed, which happens once the client is closed.
+func (ic *InstrumentsClient) StartGraphics() (<-chan map[string]any, error) {
+	// Request channel
+	channel, err := ic.dc.requestChannel(graphicsChannel)
+	if err != nil {
+		return nil, fmt.Errorf("request graphics channel: %w", err)
+	}
+
+	sub := ic.dc.subscribe(channel)
+
+	// Start
+	_, err = ic.dc.call(channel, "startSamplingAtTimeInterval:", []any{0.0}, true)
+	if err != nil {
+		ic.dc.unsubscribe(channel)
+		return nil, fmt.Errorf("invoke start sampling: %w", err)
+	}
+
+	return stream(sub), nil
+}
+
+// stream passes the samples of a channel's messages on, each of which carries a sample or a list of samples.
+func stream(sub <-chan *dtxMessage) <-chan map[string]any {
+	samples := make(chan map[string]any)
+
+	go func() {
+		defer close(samples)
+
+		for m := range sub {
+			list, ok := m.Obj.([]any)
+			if !ok {
+				list = []any{m.Obj}
+			}
+
+			for _, e := range list {
+				if sample, ok := e.(map[string]any); ok {
+					samples <- sample
+				}
+			}
+		}
+	}()
+
+	return samples
+}
@@ -0,0 +1,307 @@
+package idevice
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"howett.net/plist"
+)
+
+// NSError is an error reported by a device service.
+type NSError struct {
+	Code     int
+	Domain   string
+	UserInfo map[string]any
+}
+
+// Error ...
+func (e *NSError) Error() string {
+	if desc, ok := e.UserInfo["NSLocalizedDescription"].(string); ok {
+		return desc
+	}
+
+	return fmt.Sprintf("%s error %d", e.Domain, e.Code)
+}
+
+// archive encodes a value as NSKeyedArchiver property list. Supported are nil, booleans, numbers, strings, byte
+// slices, and slices and string-keyed maps of supported values.
+func archive(v any) ([]byte, error) {
+	a := &archiver{objects: []any{"$null"}, classes: make(map[string]plist.UID)}
+
+	root, err := a.encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return plist.Marshal(map[string]any{
+		"$version":  100000,
+		"$archiver": "NSKeyedArchiver",
+		"$top":      map[string]any{"root": root},
+		"$objects":  a.objects,
+	}, plist.BinaryFormat)
+}
+
+// archiver collects the objects of an archive.
+type archiver struct {
+	objects []any
+	classes map[string]plist.UID
+}
+
+// add adds an object to the archive and returns its UID.
+func (a *archiver) add(obj any) plist.UID {
+	a.objects = append(a.objects, obj)
+	return plist.UID(len(a.objects) - 1)
+}
+
+// class returns the UID of the class with the given name, adding it to the archive if needed.
+func (a *archiver) class(name string) plist.UID {
+	if uid, ok := a.classes[name]; ok {
+		return uid
+	}
+
+	uid := a.add(map[string]any{"$classname": name, "$classes": []string{name, "NSObject"}})
+	a.classes[name] = uid
+
+	return uid
+}
+
+// encode adds a value to the archive and returns its UID.
+func (a *archiver) encode(v any) (plist.UID, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string, []byte:
+		return a.add(v), nil
+
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+
+		return a.encode(values)
+
+	case []any:
+		values := make([]plist.UID, len(v))
+
+		for i, e := range v {
+			uid, err := a.encode(e)
+			if err != nil {
+				return 0, err
+			}
+
+			values[i] = uid
+		}
+
+		return a.add(map[string]any{"NS.objects": values, "$class": a.class("NSArray")}), nil
+
+	case map[string]any:
+		// Sort keys to get a stable encoding
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		keyUIDs := make([]plist.UID, len(keys))
+		valueUIDs := make([]plist.UID, len(keys))
+
+		for i, k := range keys {
+			uid, err := a.encode(v[k])
+			if err != nil {
+				return 0, err
+			}
+
+			keyUIDs[i] = a.add(k)
+			valueUIDs[i] = uid
+		}
+
+		return a.add(map[string]any{"NS.keys": keyUIDs, "NS.objects": valueUIDs, "$class": a.class("NSDictionary")}), nil
+
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// unarchive decodes an NSKeyedArchiver property list. Dictionaries are returned as string-keyed maps, even if their
+// keys are numbers, and objects of unknown classes as maps of their fields.
+func unarchive(data []byte) (any, error) {
+	var ka struct {
+		Objects []any                `plist:"$objects"`
+		Top     map[string]plist.UID `plist:"$top"`
+	}
+
+	if _, err := plist.Unmarshal(data, &ka); err != nil {
+		return nil, fmt.Errorf("parse archive: %w", err)
+	}
+
+	root, ok := ka.Top["root"]
+	if !ok {
+		return nil, fmt.Errorf("archive has no root object")
+	}
+
+	return (&unarchiver{objects: ka.Objects, decoding: make(map[plist.UID]bool)}).decode(root)
+}
+
+// unarchiver resolves the objects of an archive.
+type unarchiver struct {
+	objects []any
+
+	// UIDs of the objects currently being decoded, to detect reference cycles
+	decoding map[plist.UID]bool
+}
+
+// decode returns the object with the given UID.
+func (u *unarchiver) decode(uid plist.UID) (any, error) {
+	if int(uid) >= len(u.objects) {
+		return nil, fmt.Errorf("%w: invalid object reference %d", ErrMalformed, uid)
+	}
+
+	switch obj := u.objects[uid].(type) {
+	case string:
+		if obj == "$null" {
+			return nil, nil
+		}
+
+		return obj, nil
+
+	case map[string]any:
+		// An object referencing itself, directly or indirectly, would recurse forever
+		if u.decoding[uid] {
+			return nil, fmt.Errorf("%w: cyclic object reference %d", ErrMalformed, uid)
+		}
+
+		u.decoding[uid] = true
+		defer delete(u.decoding, uid)
+
+		return u.decodeObject(obj)
+
+	default:
+		return obj, nil
+	}
+}
+
+// decodeValue resolves a field value, which is either a reference or inlined.
+func (u *unarchiver) decodeValue(v any) (any, error) {
+	if uid, ok := v.(plist.UID); ok {
+		return u.decode(uid)
+	}
+
+	return v, nil
+}
+
+// decodeList resolves a list of references.
+func (u *unarchiver) decodeList(v any) ([]any, error) {
+	refs, _ := v.([]any)
+	list := make([]any, len(refs))
+
+	for i, ref := range refs {
+		e, err := u.decodeValue(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		list[i] = e
+	}
+
+	return list, nil
+}
+
+// decodeObject decodes an object by its class.
+func (u *unarchiver) decodeObject(obj map[string]any) (any, error) {
+	// Resolve class name
+	var name string
+
+	if uid, ok := obj["$class"].(plist.UID); ok && (int(uid) < len(u.objects)) {
+		if class, ok := u.objects[uid].(map[string]any); ok {
+			name, _ = class["$classname"].(string)
+		}
+	}
+
+	switch name {
+	case "NSDictionary", "NSMutableDictionary":
+		keys, err := u.decodeList(obj["NS.keys"])
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := u.decodeList(obj["NS.objects"])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(keys) != len(values) {
+			return nil, fmt.Errorf("dictionary has %d keys but %d values", len(keys), len(values))
+		}
+
+		m := make(map[string]any, len(keys))
+		for i, k := range keys {
+			m[fmt.Sprint(k)] = values[i]
+		}
+
+		return m, nil
+
+	case "NSArray", "NSMutableArray", "NSSet", "NSMutableSet":
+		return u.decodeList(obj["NS.objects"])
+
+	case "NSData", "NSMutableData":
+		return obj["NS.data"], nil
+
+	case "NSString", "NSMutableString":
+		return u.decodeValue(obj["NS.string"])
+
+	case "NSNull":
+		return nil, nil
+
+	case "NSDate":
+		t, _ := obj["NS.time"].(float64)
+		return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(t * float64(time.Second))), nil
+
+	case "NSError":
+		domain, err := u.decodeValue(obj["NSDomain"])
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := u.decodeValue(obj["NSUserInfo"])
+		if err != nil {
+			return nil, err
+		}
+
+		e := &NSError{}
+		e.Domain, _ = domain.(string)
+		e.UserInfo, _ = info.(map[string]any)
+
+		if code, ok := obj["NSCode"].(uint64); ok {
+			e.Code = int(code)
+		}
+
+		return e, nil
+
+	case "DTTapMessage", "DTSysmonTapMessage", "DTTapHeartbeatMessage":
+		// Tap messages wrap a property list
+		return u.decodeValue(obj["DTTapMessagePlist"])
+
+	default:
+		// Unknown classes are returned as maps of their fields
+		m := make(map[string]any, len(obj))
+
+		for k, v := range obj {
+			if k == "$class" {
+				continue
+			}
+
+			d, err := u.decodeValue(v)
+			if err != nil {
+				return nil, err
+			}
+
+			m[k] = d
+		}
+
+		return m, nil
+	}
+}
@@ -1,7 +1,10 @@
 package idevice
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
 )
@@ -15,6 +18,9 @@ type LockdownClient struct {
 	// Underlying lockdown client
 	ldc *libimobiledevice.LockdownClient
 
+	// Underlying connection
+	conn *serviceConn
+
 	// Related device
 	dev *Device
 }
@@ -22,7 +28,7 @@ type LockdownClient struct {
 // NewLockdownClient ...
 func NewLockdownClient(dev *Device) (*LockdownClient, error) {
 	// Create lockdown connection
-	conn, err := dev.dev.NewConnect(lockdownPort)
+	conn, err := dev.connect(lockdownPort)
 	if err != nil {
 		return nil, fmt.Errorf("create connection: %w", err)
 	}
@@ -30,17 +36,23 @@ func NewLockdownClient(dev *Device) (*LockdownClient, error) {
 	// Create client
 	ldc := libimobiledevice.NewLockdownClient(conn)
 
-	return &LockdownClient{ldc: ldc, dev: dev}, nil
+	return &LockdownClient{ldc: ldc, conn: conn, dev: dev}, nil
 }
 
 // Close ...
-func (*LockdownClient) Close() {
-	// TODO
+func (ldc *LockdownClient) Close() {
+	ldc.conn.Close()
 }
 
 // Info ...
 func (ldc *LockdownClient) Info() (any, error) {
-	// Get lockdown product version
+	return ldc.GetValue("", "")
+}
+
+// GetValue reads the lockdown value for the given domain and key. An empty domain selects the global domain, an
+// empty key returns all values of the domain.
+func (ldc *LockdownClient) GetValue(domain string, key string) (any, error) {
+	// Get lockdown value
 	var value libimobiledevice.LockdownValueResponse
 
 	err := ldc.send(
@@ -50,19 +62,53 @@ func (ldc *LockdownClient) Info() (any, error) {
 				ProtocolVersion: libimobiledevice.ProtocolVersion,
 				Request:         libimobiledevice.RequestTypeGetValue,
 			},
-			Domain: "",
-			Key:    "",
+			Domain: domain,
+			Key:    key,
 		},
 		&value,
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("get lockdown information: %w", err)
+		return nil, fmt.Errorf("get lockdown value: %w", err)
+	}
+
+	if value.Error != "" {
+		return nil, fmt.Errorf("get lockdown value (server): %w", lockdownError(value.Error))
 	}
 
 	return value.Value, nil
 }
 
+// SetValue writes the lockdown value for the given domain and key.
+func (ldc *LockdownClient) SetValue(domain string, key string, value any) error {
+	// Set lockdown value
+	var resp libimobiledevice.LockdownValueResponse
+
+	err := ldc.send(
+		&libimobiledevice.LockdownValueRequest{
+			LockdownBasicRequest: libimobiledevice.LockdownBasicRequest{
+				Label:           libimobiledevice.BundleID,
+				ProtocolVersion: libimobiledevice.ProtocolVersion,
+				Request:         libimobiledevice.RequestTypeSetValue,
+			},
+			Domain: domain,
+			Key:    key,
+			Value:  value,
+		},
+		&resp,
+	)
+
+	if err != nil {
+		return fmt.Errorf("set lockdown value: %w", err)
+	}
+
+	if resp.Error != "" {
+		return fmt.Errorf("set lockdown value (server): %w", lockdownError(resp.Error))
+	}
+
+	return nil
+}
+
 // StartSession ...
 func (ldc *LockdownClient) StartSession() (*LockdownSession, error) {
 	// Get iOS version
@@ -97,6 +143,10 @@ func (ldc *LockdownClient) StartSession() (*LockdownSession, error) {
 		return nil, fmt.Errorf("start lockdown session: %w", err)
 	}
 
+	if startSession.Error != "" {
+		return nil, fmt.Errorf("start lockdown session (server): %w", lockdownError(startSession.Error))
+	}
+
 	// Optionally enable SSL
 	if startSession.EnableSessionSSL {
 		// Enable SSL
@@ -108,6 +158,115 @@ func (ldc *LockdownClient) StartSession() (*LockdownSession, error) {
 	return newLockdownSession(ldc), nil
 }
 
+// Pair pairs the host with the device and returns the new pair record. The user has to confirm the pairing dialog
+// on the device, which is waited for until the context is canceled.
+func (ldc *LockdownClient) Pair(ctx context.Context) (*PairRecord, error) {
+	// Get device public key
+	value, err := ldc.GetValue("", "DevicePublicKey")
+	if err != nil {
+		return nil, fmt.Errorf("get device public key: %w", err)
+	}
+
+	devicePublicKey, ok := value.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected device public key: %v", value)
+	}
+
+	// Get WiFi address
+	value, err = ldc.GetValue("", "WiFiAddress")
+	if err != nil {
+		return nil, fmt.Errorf("get WiFi address: %w", err)
+	}
+
+	wifiAddress, _ := value.(string)
+
+	// Get system BUID
+	buid, err := ldc.dev.backend.ReadBUID()
+	if err != nil {
+		return nil, fmt.Errorf("read system BUID: %w", err)
+	}
+
+	// Create pair record
+	pairRecord, err := newPairRecord(devicePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("create pair record: %w", err)
+	}
+
+	pairRecord.SystemBUID = buid
+	pairRecord.WiFiMACAddress = wifiAddress
+
+	// Pair, until user responded to the pairing dialog
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		escrowBag, err := ldc.pair(libimobiledevice.RequestTypePair, pairRecord)
+		if err == nil {
+			pairRecord.EscrowBag = escrowBag
+			return pairRecord, nil
+		}
+
+		if !errors.Is(err, ErrPairingDialogResponsePending) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for pairing dialog response: %w: %w", ErrPairingDialogResponsePending, ctx.Err())
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// ValidatePair validates the given pair record with the device.
+func (ldc *LockdownClient) ValidatePair(pairRecord *PairRecord) error {
+	_, err := ldc.pair("ValidatePair", pairRecord)
+	return err
+}
+
+// Unpair removes the given pair record from the device.
+func (ldc *LockdownClient) Unpair(pairRecord *PairRecord) error {
+	_, err := ldc.pair("Unpair", pairRecord)
+	return err
+}
+
+// pair sends a pairing request of the given type, and returns the escrow bag of the response.
+func (ldc *LockdownClient) pair(request libimobiledevice.RequestType, pairRecord *PairRecord) ([]byte, error) {
+	// Pairing protocol
+	type Request struct {
+		libimobiledevice.LockdownBasicRequest
+		PairRecord     *PairRecord    `plist:"PairRecord"`
+		PairingOptions map[string]any `plist:"PairingOptions"`
+	}
+
+	// Send pairing request
+	var resp libimobiledevice.LockdownPairResponse
+
+	err := ldc.send(
+		&Request{
+			LockdownBasicRequest: libimobiledevice.LockdownBasicRequest{
+				Label:           libimobiledevice.BundleID,
+				ProtocolVersion: libimobiledevice.ProtocolVersion,
+				Request:         request,
+			},
+			PairRecord:     publicPairRecord(pairRecord),
+			PairingOptions: map[string]any{"ExtendedPairingErrors": true},
+		},
+		&resp,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("send %s request: %w", request, err)
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("send %s request (server): %w", request, lockdownError(resp.Error))
+	}
+
+	return resp.EscrowBag, nil
+}
+
 // send ...
 func (ldc *LockdownClient) send(req any, resp any) error {
 	// Create request packet
@@ -129,7 +288,7 @@ func (ldc *LockdownClient) send(req any, resp any) error {
 
 	// Parse response packet
 	if err := packetResp.Unmarshal(resp); err != nil {
-		return fmt.Errorf("unmarshal response packet: %w", err)
+		return fmt.Errorf("unmarshal response packet: %w: %w", ErrMalformed, err)
 	}
 
 	return nil
@@ -1,6 +1,7 @@
 package idevice
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
@@ -70,11 +71,16 @@ func (lds *LockdownSession) StartService(serviceName string) (libimobiledevice.I
 	}
 
 	if startService.Error != "" {
-		return nil, fmt.Errorf("start lockdown service (server): %s", startService.Error)
+		err := lockdownError(startService.Error)
+		if !errors.Is(err, ErrPasswordProtected) {
+			err = fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+		}
+
+		return nil, fmt.Errorf("start lockdown service %s (server): %w", serviceName, err)
 	}
 
 	// Create new connection
-	conn, err := lds.ldc.dev.dev.NewConnect(startService.Port)
+	conn, err := lds.ldc.dev.connect(startService.Port)
 	if err != nil {
 		return nil, fmt.Errorf("create connection: %w", err)
 	}
@@ -0,0 +1,199 @@
+package idevice
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	mdnsAddress   = "224.0.0.251:5353"
+	mdnsTypePTR   = 12
+	mdnsClassIN   = 1
+	mdnsClassQU   = 0x8000
+	mdnsMaxPacket = 9000
+)
+
+// browseMDNS sends a (legacy unicast) mDNS query for the given service, e.g. "_apple-mobdev2._tcp.local.", and returns
+// the IPv4 addresses of all hosts that answered within the given timeout.
+func browseMDNS(service string, timeout time.Duration) ([]string, error) {
+	// Create query
+	query, err := newMDNSQuery(service)
+	if err != nil {
+		return nil, fmt.Errorf("create mDNS query: %w", err)
+	}
+
+	// Open socket. Using a random port (instead of 5353) makes responders answer directly to us.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("open UDP socket: %w", err)
+	}
+
+	defer conn.Close()
+
+	// Send query
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("resolve mDNS group address: %w", err)
+	}
+
+	if _, err := conn.WriteTo(query, group); err != nil {
+		return nil, fmt.Errorf("send mDNS query: %w", err)
+	}
+
+	// Collect answers until timeout
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("set read deadline: %w", err)
+	}
+
+	var hosts []string
+	seen := make(map[string]bool)
+	buf := make([]byte, mdnsMaxPacket)
+
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+
+			return nil, fmt.Errorf("receive mDNS response: %w", err)
+		}
+
+		// Skip unrelated responses
+		if !mdnsAnswersService(buf[:n], service) {
+			continue
+		}
+
+		// Append host
+		host := addr.IP.String()
+
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts, nil
+}
+
+// newMDNSQuery creates a DNS message with a single PTR question for the given name.
+func newMDNSQuery(name string) ([]byte, error) {
+	// Header: ID 0, no flags, one question
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	// Question name
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if (len(label) == 0) || (len(label) > 63) {
+			return nil, fmt.Errorf("invalid label in name %q", name)
+		}
+
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+
+	msg = append(msg, 0)
+
+	// Question type and class (asking for unicast responses)
+	msg = binary.BigEndian.AppendUint16(msg, mdnsTypePTR)
+	msg = binary.BigEndian.AppendUint16(msg, mdnsClassIN|mdnsClassQU)
+
+	return msg, nil
+}
+
+// mdnsAnswersService returns true if the DNS message contains a PTR answer for the given service name.
+func mdnsAnswersService(msg []byte, service string) bool {
+	if len(msg) < 12 {
+		return false
+	}
+
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:]))
+	off := 12
+
+	// Skip questions
+	for i := 0; i < qdCount; i++ {
+		_, next, err := readDNSName(msg, off)
+		if err != nil {
+			return false
+		}
+
+		off = next + 4
+	}
+
+	// Check answers
+	for i := 0; i < anCount; i++ {
+		name, next, err := readDNSName(msg, off)
+		if (err != nil) || (next+10 > len(msg)) {
+			return false
+		}
+
+		typ := binary.BigEndian.Uint16(msg[next:])
+		rdLen := int(binary.BigEndian.Uint16(msg[next+8:]))
+
+		if (typ == mdnsTypePTR) && strings.EqualFold(name, service) {
+			return true
+		}
+
+		off = next + 10 + rdLen
+	}
+
+	return false
+}
+
+// readDNSName reads the (possibly compressed) domain name at the given offset of the DNS message, and returns the
+// name as well as the offset following it.
+func readDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+
+	next := -1
+
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("name exceeds message")
+		}
+
+		length := int(msg[off])
+
+		switch {
+		case length == 0:
+			// End of name
+			if next < 0 {
+				next = off + 1
+			}
+
+			return strings.Join(labels, ".") + ".", next, nil
+
+		case length&0xc0 == 0xc0:
+			// Compression pointer
+			if off+1 >= len(msg) {
+				return "", 0, fmt.Errorf("pointer exceeds message")
+			}
+
+			if next < 0 {
+				next = off + 2
+			}
+
+			jumps++
+			if jumps > 16 {
+				return "", 0, fmt.Errorf("too many compression pointers")
+			}
+
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+
+		default:
+			// Label
+			if off+1+length > len(msg) {
+				return "", 0, fmt.Errorf("label exceeds message")
+			}
+
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
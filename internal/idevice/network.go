@@ -0,0 +1,149 @@
+package idevice
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	mdnsService       = "_apple-mobdev2._tcp.local."
+	mdnsBrowseTimeout = 3 * time.Second
+)
+
+// Network connects to devices on the local network directly, without usbmuxd. Devices are either given explicitly by
+// their host address, or discovered via mDNS. As pairing requires a USB connection, pair records must be provided by
+// a pair record store.
+type Network struct {
+	// Explicit host addresses
+	hosts []string
+
+	// Discover devices via mDNS
+	browse bool
+
+	// Store for pair records
+	pairRecords PairRecordStore
+
+	// Function to return devices
+	devicesFn func() ([]*Device, error)
+}
+
+// NewNetwork creates a new Network for the given hosts. If browse is true, devices advertising the
+// "_apple-mobdev2._tcp" service via mDNS are added as well.
+func NewNetwork(hosts []string, browse bool, pairRecords PairRecordStore) *Network {
+	// Return new client
+	n := &Network{hosts: hosts, browse: browse, pairRecords: pairRecords}
+
+	n.devicesFn = n.devicesFnOnce()
+
+	return n
+}
+
+// Devices ...
+func (n *Network) Devices() ([]*Device, error) {
+	return n.devicesFn()
+}
+
+// ReadBUID ...
+func (*Network) ReadBUID() (string, error) {
+	return "", fmt.Errorf("read BUID: not supported for direct network connections")
+}
+
+// connect opens a connection to the given port of the device.
+func (*Network) connect(dev *Device, port int) (*serviceConn, error) {
+	// Dial device
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(dev.address, strconv.Itoa(port)), defaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial device: %w", err)
+	}
+
+	return newServiceConn(conn), nil
+}
+
+// pairRecordStore ...
+func (n *Network) pairRecordStore() PairRecordStore {
+	return n.pairRecords
+}
+
+// devicesFnOnce ...
+func (n *Network) devicesFnOnce() func() ([]*Device, error) {
+	var devices []*Device
+	var err error
+	var once sync.Once
+
+	// return function that created devices once
+	return func() ([]*Device, error) {
+		once.Do(func() {
+			seen := make(map[string]bool)
+
+			// Explicit hosts
+			for _, host := range n.hosts {
+				dev, innerErr := n.newDevice(host)
+				if innerErr != nil {
+					err = fmt.Errorf("connect to host %s: %w", host, innerErr)
+					return
+				}
+
+				if !seen[dev.UDID()] {
+					seen[dev.UDID()] = true
+					devices = append(devices, dev)
+				}
+			}
+
+			if !n.browse {
+				return
+			}
+
+			// Discovered hosts
+			hosts, innerErr := browseMDNS(mdnsService, mdnsBrowseTimeout)
+			if innerErr != nil {
+				err = fmt.Errorf("browse mDNS: %w", innerErr)
+				return
+			}
+
+			for _, host := range hosts {
+				// Skip hosts that don't respond properly (e.g. Macs also advertise this service)
+				dev, innerErr := n.newDevice(host)
+				if innerErr != nil {
+					continue
+				}
+
+				if !seen[dev.UDID()] {
+					seen[dev.UDID()] = true
+					devices = append(devices, dev)
+				}
+			}
+		})
+
+		return devices, err
+	}
+}
+
+// newDevice creates a device for the given host address, and reads its UDID via lockdown.
+func (n *Network) newDevice(host string) (*Device, error) {
+	// Create device
+	dev := newDevice("", "Network", n)
+	dev.address = host
+
+	// Read UDID, which is needed to find the pair record
+	ldc, err := dev.internalLockdownClientFn()
+	if err != nil {
+		return nil, fmt.Errorf("get internal lockdown client: %w", err)
+	}
+
+	value, err := ldc.GetValue("", "UniqueDeviceID")
+	if err != nil {
+		return nil, fmt.Errorf("get unique device ID: %w", err)
+	}
+
+	udid, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected unique device ID: %v", value)
+	}
+
+	dev.udid = udid
+
+	return dev, nil
+}
@@ -0,0 +1,106 @@
+package idevice
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+	"howett.net/plist"
+)
+
+// notificationProxyServiceName is the name of the notification proxy service.
+const notificationProxyServiceName = "com.apple.mobile.notification_proxy"
+
+// NotificationProxyClient talks to the notification proxy service of a device, which relays notifications observed by
+// the client.
+type NotificationProxyClient struct {
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+}
+
+// notificationProxyMessage is a message from or to the notification proxy service.
+type notificationProxyMessage struct {
+	Command string `plist:"Command"`
+	Name    string `plist:"Name,omitempty"`
+}
+
+// StartNotificationProxyService starts the notification proxy service.
+func (lds *LockdownSession) StartNotificationProxyService() (*NotificationProxyClient, error) {
+	// Start service
+	conn, err := lds.StartService(notificationProxyServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	// Notifications arrive whenever something happens
+	conn.Timeout(0)
+
+	if err := conn.RawConn().SetReadDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("clear read deadline: %w", err)
+	}
+
+	return &NotificationProxyClient{conn: conn}, nil
+}
+
+// Close ...
+func (npc *NotificationProxyClient) Close() {
+	npc.conn.Close()
+}
+
+// Observe asks the device to relay the notifications with the given names.
+func (npc *NotificationProxyClient) Observe(names ...string) error {
+	for _, name := range names {
+		if err := npc.send(&notificationProxyMessage{Command: "ObserveNotification", Name: name}); err != nil {
+			return fmt.Errorf("observe %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadNotification blocks until the next observed notification arrives, and returns its name.
+func (npc *NotificationProxyClient) ReadNotification() (string, error) {
+	for {
+		// Receive message, a length-prefixed property list
+		lenRaw, err := npc.conn.Read(4)
+		if err != nil {
+			return "", fmt.Errorf("receive message length: %w", err)
+		}
+
+		data, err := npc.conn.Read(int(binary.BigEndian.Uint32(lenRaw)))
+		if err != nil {
+			return "", fmt.Errorf("receive message body: %w", err)
+		}
+
+		var msg notificationProxyMessage
+
+		if _, err := plist.Unmarshal(data, &msg); err != nil {
+			return "", fmt.Errorf("unmarshal message: %w: %w", ErrMalformed, err)
+		}
+
+		switch msg.Command {
+		case "RelayNotification":
+			return msg.Name, nil
+
+		case "ProxyDeath":
+			return "", errors.New("notification proxy died")
+		}
+	}
+}
+
+// send sends a message as length-prefixed XML property list.
+func (npc *NotificationProxyClient) send(msg *notificationProxyMessage) error {
+	data, err := plist.Marshal(msg, plist.XMLFormat)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	if err := npc.conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package idevice
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+// PairRecord contains host and device certificates, as well as the identifiers a host uses to authenticate against
+// a device.
+type PairRecord = libimobiledevice.PairRecord
+
+// newPairRecord creates a new pair record for the device with the given PEM encoded public key. Root and host
+// certificates are generated from scratch, the device certificate is signed by the root certificate.
+func newPairRecord(devicePublicKey []byte) (*PairRecord, error) {
+	// Parse device public key
+	block, _ := pem.Decode(devicePublicKey)
+	if block == nil {
+		return nil, fmt.Errorf("decode device public key: no PEM data")
+	}
+
+	deviceKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse device public key: %w", err)
+	}
+
+	// Generate root and host keys
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate root key: %w", err)
+	}
+
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate host key: %w", err)
+	}
+
+	// Templates
+	notBefore := time.Now()
+	notAfter := notBefore.AddDate(10, 0, 0)
+
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    x509.SHA256WithRSA,
+	}
+
+	subjectKeyID := sha1.Sum(rootKey.N.Bytes()) //nolint
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(0),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          subjectKeyID[:],
+		SignatureAlgorithm:    x509.SHA256WithRSA,
+	}
+
+	// Create certificates
+	rootCert, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("create root certificate: %w", err)
+	}
+
+	hostCert, err := x509.CreateCertificate(rand.Reader, leafTemplate, rootTemplate, &hostKey.PublicKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("create host certificate: %w", err)
+	}
+
+	deviceCert, err := x509.CreateCertificate(rand.Reader, leafTemplate, rootTemplate, deviceKey, rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("create device certificate: %w", err)
+	}
+
+	// Create host ID
+	hostID, err := newHostID()
+	if err != nil {
+		return nil, fmt.Errorf("create host ID: %w", err)
+	}
+
+	return &PairRecord{
+		DeviceCertificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: deviceCert}),
+		HostCertificate:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: hostCert}),
+		HostPrivateKey:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(hostKey)}),
+		RootCertificate:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCert}),
+		RootPrivateKey:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rootKey)}),
+		HostID:            hostID,
+	}, nil
+}
+
+// publicPairRecord returns a copy of the pair record without private keys, suitable to be sent to the device.
+func publicPairRecord(pairRecord *PairRecord) *PairRecord {
+	pr := *pairRecord
+
+	pr.HostPrivateKey = nil
+	pr.RootPrivateKey = nil
+	pr.EscrowBag = nil
+
+	return &pr
+}
+
+// newHostID creates a new random (version 4) UUID in upper case, as used for host IDs.
+func newHostID() (string, error) {
+	// Read random bytes
+	var u [16]byte
+
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+
+	// Set version and variant
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])), nil
+}
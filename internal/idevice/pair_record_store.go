@@ -0,0 +1,258 @@
+package idevice
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+	"howett.net/plist"
+)
+
+// PairRecordStore loads and saves pair records, keyed by device UDID.
+type PairRecordStore interface {
+	// ReadPairRecord reads the pair record of the device with the given UDID. ErrPairRecordNotFound is returned if
+	// the store doesn't contain a pair record for that device.
+	ReadPairRecord(udid string) (*PairRecord, error)
+
+	// SavePairRecord saves the pair record of the device with the given UDID.
+	SavePairRecord(udid string, pairRecord *PairRecord) error
+
+	// DeletePairRecord deletes the pair record of the device with the given UDID.
+	DeletePairRecord(udid string) error
+}
+
+// NewPairRecordStore creates a store for pair records at the given path. If path is a directory, pair records are
+// stored as "<UDID>.plist" files within that directory, using the same layout as libimobiledevice (e.g. at
+// "/var/lib/lockdown"). Otherwise, path is a single pair record file that is used for all devices. A path that
+// doesn't exist yet is a directory, unless it has a ".plist" extension and no trailing separator. Both are created
+// once a pair record is saved.
+func NewPairRecordStore(path string) (PairRecordStore, error) {
+	// Check path
+	fi, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("stat pair record path: %w", err)
+	}
+
+	switch {
+	case fi != nil:
+		if fi.IsDir() {
+			return &dirPairRecordStore{dir: path}, nil
+		}
+
+		return &filePairRecordStore{path: path}, nil
+
+	case strings.HasSuffix(path, string(filepath.Separator)) || (filepath.Ext(path) != ".plist"):
+		return &dirPairRecordStore{dir: path}, nil
+
+	default:
+		return &filePairRecordStore{path: path}, nil
+	}
+}
+
+// NewDefaultPairRecordStore creates a store for pair records at the platform's default location of libimobiledevice
+// and usbmuxd ("/var/db/lockdown" on macOS, "/var/lib/lockdown" otherwise).
+func NewDefaultPairRecordStore() PairRecordStore {
+	if runtime.GOOS == "darwin" {
+		return &dirPairRecordStore{dir: "/var/db/lockdown"}
+	}
+
+	return &dirPairRecordStore{dir: "/var/lib/lockdown"}
+}
+
+// dirPairRecordStore stores pair records as "<UDID>.plist" files within a directory.
+type dirPairRecordStore struct {
+	dir string // Directory
+}
+
+// ReadPairRecord ...
+func (s *dirPairRecordStore) ReadPairRecord(udid string) (*PairRecord, error) {
+	return readPairRecordFile(filepath.Join(s.dir, udid+".plist"))
+}
+
+// SavePairRecord ...
+func (s *dirPairRecordStore) SavePairRecord(udid string, pairRecord *PairRecord) error {
+	// Create directory
+	err := os.MkdirAll(s.dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("create pair record directory: %w", err)
+	}
+
+	return writePairRecordFile(filepath.Join(s.dir, udid+".plist"), pairRecord)
+}
+
+// DeletePairRecord ...
+func (s *dirPairRecordStore) DeletePairRecord(udid string) error {
+	// Delete file
+	err := os.Remove(filepath.Join(s.dir, udid+".plist"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrPairRecordNotFound
+		}
+
+		return fmt.Errorf("delete pair record file: %w", err)
+	}
+
+	return nil
+}
+
+// filePairRecordStore stores a single pair record in a file, that is used for all devices.
+type filePairRecordStore struct {
+	path string // File path
+}
+
+// ReadPairRecord ...
+func (s *filePairRecordStore) ReadPairRecord(_ string) (*PairRecord, error) {
+	return readPairRecordFile(s.path)
+}
+
+// SavePairRecord ...
+func (s *filePairRecordStore) SavePairRecord(_ string, pairRecord *PairRecord) error {
+	return writePairRecordFile(s.path, pairRecord)
+}
+
+// DeletePairRecord ...
+func (s *filePairRecordStore) DeletePairRecord(_ string) error {
+	// Delete file
+	err := os.Remove(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrPairRecordNotFound
+		}
+
+		return fmt.Errorf("delete pair record file: %w", err)
+	}
+
+	return nil
+}
+
+// usbmuxPairRecordStore stores pair records with usbmuxd.
+type usbmuxPairRecordStore struct {
+	mux *USBMux // USBMux
+}
+
+// ReadPairRecord ...
+func (s *usbmuxPairRecordStore) ReadPairRecord(udid string) (*PairRecord, error) {
+	// Read pair record
+	pairRecord, err := s.mux.readPairRecord(udid)
+	if err != nil {
+		// usbmuxd reports missing pair records as "bad device"
+		var resErr usbmuxResultError
+		if errors.As(err, &resErr) && (libimobiledevice.ReplyCode(resErr) == libimobiledevice.ReplyCodeBadDevice) {
+			return nil, ErrPairRecordNotFound
+		}
+
+		return nil, fmt.Errorf("read pair record from usbmuxd: %w", err)
+	}
+
+	return pairRecord, nil
+}
+
+// SavePairRecord ...
+func (s *usbmuxPairRecordStore) SavePairRecord(udid string, pairRecord *PairRecord) error {
+	// Find device
+	dev, err := s.mux.device(udid)
+	if err != nil {
+		return fmt.Errorf("find device: %w", err)
+	}
+
+	// Save pair record
+	err = s.mux.savePairRecord(udid, dev.deviceID, pairRecord)
+	if err != nil {
+		return fmt.Errorf("save pair record to usbmuxd: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePairRecord ...
+func (s *usbmuxPairRecordStore) DeletePairRecord(udid string) error {
+	// Delete pair record
+	err := s.mux.deletePairRecord(udid)
+	if err != nil {
+		return fmt.Errorf("delete pair record from usbmuxd: %w", err)
+	}
+
+	return nil
+}
+
+// fallbackPairRecordStore reads pair records from a primary store, and falls back to a secondary store if the pair
+// record could not be found. Pair records are deleted the same way, but only ever saved to the primary store.
+type fallbackPairRecordStore struct {
+	primary   PairRecordStore // Primary store
+	secondary PairRecordStore // Secondary store
+}
+
+// ReadPairRecord ...
+func (s *fallbackPairRecordStore) ReadPairRecord(udid string) (*PairRecord, error) {
+	// Read from primary store
+	pairRecord, err := s.primary.ReadPairRecord(udid)
+	if !errors.Is(err, ErrPairRecordNotFound) {
+		return pairRecord, err
+	}
+
+	// Read from secondary store
+	return s.secondary.ReadPairRecord(udid)
+}
+
+// SavePairRecord ...
+func (s *fallbackPairRecordStore) SavePairRecord(udid string, pairRecord *PairRecord) error {
+	return s.primary.SavePairRecord(udid, pairRecord)
+}
+
+// DeletePairRecord ...
+func (s *fallbackPairRecordStore) DeletePairRecord(udid string) error {
+	// Delete from primary store
+	err := s.primary.DeletePairRecord(udid)
+	if !errors.Is(err, ErrPairRecordNotFound) {
+		return err
+	}
+
+	// Delete from secondary store
+	return s.secondary.DeletePairRecord(udid)
+}
+
+// readPairRecordFile reads a pair record from the plist file at the given path.
+func readPairRecordFile(path string) (*PairRecord, error) {
+	// Read file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrPairRecordNotFound
+		}
+
+		return nil, fmt.Errorf("read pair record file: %w", err)
+	}
+
+	// Parse pair record
+	var pairRecord PairRecord
+
+	_, err = plist.Unmarshal(data, &pairRecord)
+	if err != nil {
+		return nil, fmt.Errorf("parse pair record file: %w", err)
+	}
+
+	return &pairRecord, nil
+}
+
+// writePairRecordFile writes a pair record to the plist file at the given path. The file is only readable by the
+// current user, as it contains private keys.
+func writePairRecordFile(path string, pairRecord *PairRecord) error {
+	// Serialize pair record
+	data, err := plist.MarshalIndent(pairRecord, plist.XMLFormat, "\t")
+	if err != nil {
+		return fmt.Errorf("serialize pair record: %w", err)
+	}
+
+	// Write file
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("write pair record file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package idevice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+	"howett.net/plist"
+)
+
+// Size of the packet header without and with the timestamp, which older devices don't send.
+const (
+	pcapdHeaderSize     = 87
+	pcapdTimestampedEnd = 95
+)
+
+// pcapdFamilyIPv6 is the protocol family of captured IPv6 packets.
+const pcapdFamilyIPv6 = 30
+
+// PcapdClient talks to the packet capture service of a device, which streams all packets sent or received on any
+// network interface of the device.
+type PcapdClient struct {
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+}
+
+// Packet is a network packet captured on a device.
+type Packet struct {
+	// Time the packet was captured.
+	Time time.Time
+
+	// Interface the packet was captured on, e.g. "en0" or "pdp_ip0".
+	Interface string
+
+	// Outgoing is true for sent packets, and false for received packets.
+	Outgoing bool
+
+	// Process that sent or received the packet, and the process it did so on behalf of (if any).
+	PID              int
+	Process          string
+	EffectivePID     int
+	EffectiveProcess string
+
+	// Length of the packet on the wire (in bytes).
+	Length int
+
+	// Frame is the captured packet, starting with an Ethernet header. Packets of interfaces without link layer, e.g.
+	// cellular, get a made-up Ethernet header.
+	Frame []byte
+}
+
+// StartPcapdService starts the packet capture service.
+func (lds *LockdownSession) StartPcapdService() (*PcapdClient, error) {
+	// Start service
+	conn, err := lds.StartService(libimobiledevice.PcapdServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	// Packets arrive whenever something is sent or received
+	conn.Timeout(0)
+
+	if err := conn.RawConn().SetReadDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("clear read deadline: %w", err)
+	}
+
+	return &PcapdClient{conn: conn}, nil
+}
+
+// Close ...
+func (pc *PcapdClient) Close() {
+	pc.conn.Close()
+}
+
+// ReadPacket blocks until the next packet arrives, and returns it.
+func (pc *PcapdClient) ReadPacket() (*Packet, error) {
+	// Receive message, a length-prefixed property list containing the packet
+	lenRaw, err := pc.conn.Read(4)
+	if err != nil {
+		return nil, fmt.Errorf("receive message length: %w", err)
+	}
+
+	msg, err := pc.conn.Read(int(binary.BigEndian.Uint32(lenRaw)))
+	if err != nil {
+		return nil, fmt.Errorf("receive message body: %w", err)
+	}
+
+	var data []byte
+
+	if _, err := plist.Unmarshal(msg, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal message: %w: %w", ErrMalformed, err)
+	}
+
+	return parsePacket(data)
+}
+
+// parsePacket parses a packet and its header. All fields of the header are big endian, except for the PIDs.
+func parsePacket(data []byte) (*Packet, error) {
+	if len(data) < pcapdHeaderSize {
+		return nil, fmt.Errorf("packet header too short: %d bytes", len(data))
+	}
+
+	hdrSize := int(binary.BigEndian.Uint32(data[0:4]))
+	length := int(binary.BigEndian.Uint32(data[5:9]))
+	family := binary.BigEndian.Uint32(data[13:17])
+	preLength := binary.BigEndian.Uint32(data[17:21])
+
+	if (hdrSize < pcapdHeaderSize) || (hdrSize > len(data)) {
+		return nil, fmt.Errorf("invalid packet header size: %d bytes", hdrSize)
+	}
+
+	p := &Packet{
+		Time:             time.Now(),
+		Interface:        cString(data[25:41]),
+		Outgoing:         data[12] == 0x10,
+		PID:              int(int32(binary.LittleEndian.Uint32(data[41:45]))),
+		Process:          cString(data[45:62]),
+		EffectivePID:     int(int32(binary.LittleEndian.Uint32(data[66:70]))),
+		EffectiveProcess: cString(data[70:87]),
+		Length:           length,
+	}
+
+	if hdrSize >= pcapdTimestampedEnd {
+		secs := binary.BigEndian.Uint32(data[87:91])
+		usecs := binary.BigEndian.Uint32(data[91:95])
+		p.Time = time.Unix(int64(secs), int64(usecs)*int64(time.Microsecond))
+	}
+
+	// Prepend Ethernet header to packets without link layer
+	payload := data[hdrSize:]
+
+	if preLength == 0 {
+		etherType := []byte{0x08, 0x00}
+		if family == pcapdFamilyIPv6 {
+			etherType = []byte{0x86, 0xdd}
+		}
+
+		frame := make([]byte, 0, 14+len(payload))
+		frame = append(frame, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe, 0xbe, 0xfe)
+		frame = append(frame, etherType...)
+		frame = append(frame, payload...)
+
+		p.Frame = frame
+		p.Length += 14
+	} else {
+		p.Frame = payload
+	}
+
+	return p, nil
+}
+
+// cString converts a NUL-terminated string.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+
+	return string(b)
+}
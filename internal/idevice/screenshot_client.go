@@ -0,0 +1,133 @@
+package idevice
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+	"howett.net/plist"
+)
+
+// deviceLinkVersion is the version of the DeviceLink protocol spoken by the screenshot service.
+const deviceLinkVersion = 300
+
+// ScreenshotClient talks to the screenshot service of a device. This requires the developer disk image to be
+// mounted.
+type ScreenshotClient struct {
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+}
+
+// StartScreenshotService starts the screenshot service, and exchanges versions of the DeviceLink protocol.
+func (lds *LockdownSession) StartScreenshotService() (*ScreenshotClient, error) {
+	// Start service
+	conn, err := lds.StartService(libimobiledevice.ScreenshotServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	sc := &ScreenshotClient{conn: conn}
+
+	// Exchange versions
+	msg, err := sc.receive()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("receive version: %w", err)
+	}
+
+	if (len(msg) < 1) || (msg[0] != "DLMessageVersionExchange") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected version message: %v", msg)
+	}
+
+	err = sc.send([]any{"DLMessageVersionExchange", "DLVersionsOk", deviceLinkVersion})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send version: %w", err)
+	}
+
+	// Wait until ready
+	msg, err = sc.receive()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("receive ready: %w", err)
+	}
+
+	if (len(msg) < 1) || (msg[0] != "DLMessageDeviceReady") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected ready message: %v", msg)
+	}
+
+	return sc, nil
+}
+
+// Close ...
+func (sc *ScreenshotClient) Close() {
+	sc.conn.Close()
+}
+
+// TakeScreenshot takes a screenshot, and returns it as PNG or TIFF image, depending on the device.
+func (sc *ScreenshotClient) TakeScreenshot() ([]byte, error) {
+	// Request screenshot
+	err := sc.send([]any{"DLMessageProcessMessage", map[string]any{"MessageType": "ScreenShotRequest"}})
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	// Receive screenshot
+	msg, err := sc.receive()
+	if err != nil {
+		return nil, fmt.Errorf("receive reply: %w", err)
+	}
+
+	if len(msg) < 2 {
+		return nil, fmt.Errorf("unexpected reply: %v", msg)
+	}
+
+	reply, ok := msg[1].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply: %v", msg)
+	}
+
+	data, ok := reply["ScreenShotData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("reply without screenshot: %v", reply["MessageType"])
+	}
+
+	return data, nil
+}
+
+// send sends a DeviceLink message as length-prefixed binary property list.
+func (sc *ScreenshotClient) send(msg []any) error {
+	data, err := plist.Marshal(msg, plist.BinaryFormat)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	if err := sc.conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	return nil
+}
+
+// receive receives a DeviceLink message.
+func (sc *ScreenshotClient) receive() ([]any, error) {
+	lenRaw, err := sc.conn.Read(4)
+	if err != nil {
+		return nil, fmt.Errorf("receive message length: %w", err)
+	}
+
+	data, err := sc.conn.Read(int(binary.BigEndian.Uint32(lenRaw)))
+	if err != nil {
+		return nil, fmt.Errorf("receive message body: %w", err)
+	}
+
+	var msg []any
+
+	if _, err := plist.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("unmarshal message: %w: %w", ErrMalformed, err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,59 @@
+package idevice
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+)
+
+// SyslogRelayClient talks to the syslog relay service of a device, which streams the device log as text lines.
+type SyslogRelayClient struct {
+	// Underlying connection
+	conn libimobiledevice.InnerConn
+
+	// Buffered reader of the connection
+	r *bufio.Reader
+}
+
+// StartSyslogRelayService starts the syslog relay service.
+func (lds *LockdownSession) StartSyslogRelayService() (*SyslogRelayClient, error) {
+	// Start service
+	conn, err := lds.StartService(libimobiledevice.SyslogRelayServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("start service: %w", err)
+	}
+
+	// Lines arrive whenever something is logged
+	conn.Timeout(0)
+
+	if err := conn.RawConn().SetReadDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("clear read deadline: %w", err)
+	}
+
+	return &SyslogRelayClient{conn: conn, r: bufio.NewReader(conn.RawConn())}, nil
+}
+
+// Close ...
+func (src *SyslogRelayClient) Close() {
+	src.conn.Close()
+}
+
+// ReadLine blocks until the next log line arrives, and returns it.
+func (src *SyslogRelayClient) ReadLine() (string, error) {
+	for {
+		line, err := src.r.ReadBytes('\n')
+		if err != nil {
+			return "", fmt.Errorf("read line: %w: %w", ErrConnectionLost, err)
+		}
+
+		// Lines are separated by NUL bytes
+		line = bytes.Trim(line, "\x00\r\n")
+		if len(line) > 0 {
+			return string(line), nil
+		}
+	}
+}
@@ -1,31 +1,65 @@
 package idevice
 
 import (
+	"encoding/binary"
 	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
 	"sync"
 
-	giDevice "github.com/electricbubble/gidevice"
+	"github.com/electricbubble/gidevice/pkg/libimobiledevice"
+	"howett.net/plist"
+)
+
+const (
+	usbmuxAddressEnv = "USBMUXD_SOCKET_ADDRESS"
+
+	// usbmuxHeaderLength is the length of the header of a usbmuxd packet (in bytes).
+	usbmuxHeaderLength = 16
+
+	// usbmuxMaxLength limits the length of a usbmuxd packet, including its header (in bytes).
+	usbmuxMaxLength = 16 << 20
 )
 
 // USBMux ...
 type USBMux struct {
-	// Underlying USBMUX
-	usbmux giDevice.Usbmux
+	// Network and address of the usbmuxd endpoint
+	network string
+	address string
+
+	// Store for pair records
+	pairRecords PairRecordStore
 
 	// Function to return devices
 	devicesFn func() ([]*Device, error)
 }
 
-// NewUSBMux ...
-func NewUSBMux() (*USBMux, error) {
-	// Create USBMUX connection
-	usbmux, err := giDevice.NewUsbmux()
+// NewUSBMux creates a new USBMux. The address selects the usbmuxd endpoint, either as "unix:/path/to/socket" or as
+// "tcp:host:port". If empty, the address is read from the USBMUXD_SOCKET_ADDRESS environment variable, and falls
+// back to the platform's default socket.
+//
+// Pair records are read from the given store first, and from usbmuxd if the store doesn't contain them. If the store
+// is nil, pair records are read from usbmuxd only.
+func NewUSBMux(address string, pairRecords PairRecordStore) (*USBMux, error) {
+	// Parse address
+	if address == "" {
+		address = os.Getenv(usbmuxAddressEnv)
+	}
+
+	network, address, err := parseUSBMuxAddress(address)
 	if err != nil {
-		return nil, fmt.Errorf("create USBMux connection: %w", err)
+		return nil, fmt.Errorf("parse USBMux address: %w", err)
 	}
 
 	// Return new client
-	c := &USBMux{usbmux: usbmux}
+	c := &USBMux{network: network, address: address}
+
+	c.pairRecords = &usbmuxPairRecordStore{mux: c}
+	if pairRecords != nil {
+		c.pairRecords = &fallbackPairRecordStore{primary: pairRecords, secondary: c.pairRecords}
+	}
 
 	c.devicesFn = c.devicesFnOnce()
 
@@ -37,6 +71,146 @@ func (mux *USBMux) Devices() ([]*Device, error) {
 	return mux.devicesFn()
 }
 
+// device returns the device with the given UDID.
+func (mux *USBMux) device(udid string) (*Device, error) {
+	// Get all devices
+	devices, err := mux.devicesFn()
+	if err != nil {
+		return nil, fmt.Errorf("get devices: %w", err)
+	}
+
+	// Find device
+	for _, dev := range devices {
+		if dev.UDID() == udid {
+			return dev, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, udid)
+}
+
+// ReadBUID reads the system BUID of the host.
+func (mux *USBMux) ReadBUID() (string, error) {
+	// Read BUID
+	var resp struct {
+		BUID string `plist:"BUID"`
+	}
+
+	err := mux.request(newUSBMuxRequest(libimobiledevice.MessageTypeReadBUID), &resp)
+	if err != nil {
+		return "", fmt.Errorf("read BUID: %w", err)
+	}
+
+	return resp.BUID, nil
+}
+
+// connect opens a connection to the given port of the device.
+func (mux *USBMux) connect(dev *Device, port int) (*serviceConn, error) {
+	// Dial usbmuxd
+	conn, err := mux.dial()
+	if err != nil {
+		return nil, fmt.Errorf("dial usbmuxd: %w", err)
+	}
+
+	// Connect to device port
+	err = mux.roundTrip(
+		conn,
+		&libimobiledevice.ConnectRequest{
+			BasicRequest: *newUSBMuxRequest(libimobiledevice.MessageTypeConnect),
+			DeviceID:     dev.deviceID,
+			PortNumber:   int((uint16(port) << 8) | (uint16(port) >> 8)),
+		},
+		nil,
+	)
+
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("connect to device port %d: %w", port, err)
+	}
+
+	// From now on, the connection is tunneled to the device
+	return newServiceConn(conn), nil
+}
+
+// pairRecordStore ...
+func (mux *USBMux) pairRecordStore() PairRecordStore {
+	return mux.pairRecords
+}
+
+// readPairRecord reads the pair record of the device with the given UDID from usbmuxd.
+func (mux *USBMux) readPairRecord(udid string) (*PairRecord, error) {
+	// Read pair record
+	var resp struct {
+		PairRecordData []byte `plist:"PairRecordData"`
+	}
+
+	err := mux.request(
+		&libimobiledevice.ReadPairRecordRequest{
+			BasicRequest: *newUSBMuxRequest(libimobiledevice.MessageTypeReadPairRecord),
+			PairRecordID: udid,
+		},
+		&resp,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("read pair record: %w", err)
+	}
+
+	// Parse pair record
+	var pairRecord PairRecord
+
+	_, err = plist.Unmarshal(resp.PairRecordData, &pairRecord)
+	if err != nil {
+		return nil, fmt.Errorf("parse pair record: %w", err)
+	}
+
+	return &pairRecord, nil
+}
+
+// savePairRecord saves the pair record of the device with the given UDID and (usbmuxd) device ID to usbmuxd.
+func (mux *USBMux) savePairRecord(udid string, deviceID int, pairRecord *PairRecord) error {
+	// Serialize pair record
+	data, err := plist.Marshal(pairRecord, plist.XMLFormat)
+	if err != nil {
+		return fmt.Errorf("serialize pair record: %w", err)
+	}
+
+	// Save pair record
+	err = mux.request(
+		&libimobiledevice.SavePairRecordRequest{
+			BasicRequest:   *newUSBMuxRequest(libimobiledevice.MessageTypeSavePairRecord),
+			PairRecordID:   udid,
+			PairRecordData: data,
+			DeviceID:       deviceID,
+		},
+		nil,
+	)
+
+	if err != nil {
+		return fmt.Errorf("save pair record: %w", err)
+	}
+
+	return nil
+}
+
+// deletePairRecord deletes the pair record of the device with the given UDID from usbmuxd.
+func (mux *USBMux) deletePairRecord(udid string) error {
+	// Delete pair record
+	err := mux.request(
+		&libimobiledevice.DeletePairRecordRequest{
+			BasicRequest: *newUSBMuxRequest(libimobiledevice.MessageTypeDeletePairRecord),
+			PairRecordID: udid,
+		},
+		nil,
+	)
+
+	if err != nil {
+		return fmt.Errorf("delete pair record: %w", err)
+	}
+
+	return nil
+}
+
 // devicesFnOnce ...
 func (mux *USBMux) devicesFnOnce() func() ([]*Device, error) {
 	var devices []*Device
@@ -47,20 +221,169 @@ func (mux *USBMux) devicesFnOnce() func() ([]*Device, error) {
 	return func() ([]*Device, error) {
 		once.Do(func() {
 			// Get all devices
-			innerDevices, innerErr := mux.usbmux.Devices()
+			var resp struct {
+				DeviceList []libimobiledevice.BaseDevice `plist:"DeviceList"`
+			}
+
+			innerErr := mux.request(newUSBMuxRequest(libimobiledevice.MessageTypeDeviceList), &resp)
 			if innerErr != nil {
 				err = fmt.Errorf("read devices: %w", innerErr)
 				return
 			}
 
 			// Wrap devices
-			devices = make([]*Device, len(innerDevices))
+			devices = make([]*Device, len(resp.DeviceList))
 
-			for i, dev := range innerDevices {
-				devices[i] = newDevice(dev)
+			for i, dev := range resp.DeviceList {
+				devices[i] = newDevice(dev.Properties.SerialNumber, dev.Properties.ConnectionType, mux)
+				devices[i].deviceID = dev.Properties.DeviceID
 			}
 		})
 
 		return devices, err
 	}
 }
+
+// request dials usbmuxd, sends a single request and receives the response.
+func (mux *USBMux) request(req any, resp any) error {
+	// Dial usbmuxd
+	conn, err := mux.dial()
+	if err != nil {
+		return fmt.Errorf("dial usbmuxd: %w", err)
+	}
+
+	defer conn.Close()
+
+	return mux.roundTrip(conn, req, resp)
+}
+
+// dial opens a new connection to usbmuxd.
+func (mux *USBMux) dial() (net.Conn, error) {
+	return net.DialTimeout(mux.network, mux.address, defaultTimeout)
+}
+
+// roundTrip sends a request on the given usbmuxd connection and receives the response. If resp is nil, the response
+// is expected to be a result message.
+func (*USBMux) roundTrip(conn net.Conn, req any, resp any) error {
+	sc := newServiceConn(conn)
+
+	// Create request packet
+	body, err := plist.Marshal(req, plist.XMLFormat)
+	if err != nil {
+		return fmt.Errorf("create request packet: %w", err)
+	}
+
+	header := make([]byte, usbmuxHeaderLength)
+
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(header)+len(body)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(libimobiledevice.ProtoVersionPlist))
+	binary.LittleEndian.PutUint32(header[8:], uint32(libimobiledevice.ProtoMessageTypePlist))
+	binary.LittleEndian.PutUint32(header[12:], 1)
+
+	// Send request packet
+	if err := sc.Write(append(header, body...)); err != nil {
+		return fmt.Errorf("send request packet: %w", err)
+	}
+
+	// Receive response packet
+	header, err = sc.Read(usbmuxHeaderLength)
+	if err != nil {
+		return fmt.Errorf("receive response header: %w", err)
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:])
+	if (length < usbmuxHeaderLength) || (length > usbmuxMaxLength) {
+		return fmt.Errorf("%w: bad response length: %d bytes", ErrMalformed, length)
+	}
+
+	body, err = sc.Read(int(length) - usbmuxHeaderLength)
+	if err != nil {
+		return fmt.Errorf("receive response body: %w", err)
+	}
+
+	// Check result
+	var result struct {
+		MessageType libimobiledevice.MessageType `plist:"MessageType"`
+		Number      libimobiledevice.ReplyCode   `plist:"Number"`
+	}
+
+	if _, err := plist.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("unmarshal response packet: %w: %w", ErrMalformed, err)
+	}
+
+	if (result.MessageType == libimobiledevice.MessageTypeResult) && (result.Number != libimobiledevice.ReplyCodeOK) {
+		return usbmuxResultError(result.Number)
+	}
+
+	// Parse response packet
+	if resp == nil {
+		return nil
+	}
+
+	if _, err := plist.Unmarshal(body, resp); err != nil {
+		return fmt.Errorf("unmarshal response packet: %w: %w", ErrMalformed, err)
+	}
+
+	return nil
+}
+
+// newUSBMuxRequest creates the basic usbmuxd request of the given message type.
+func newUSBMuxRequest(msgType libimobiledevice.MessageType) *libimobiledevice.BasicRequest {
+	return &libimobiledevice.BasicRequest{
+		MessageType:         msgType,
+		BundleID:            libimobiledevice.BundleID,
+		ProgramName:         libimobiledevice.ProgramName,
+		ClientVersionString: libimobiledevice.ClientVersion,
+		LibUSBMuxVersion:    libimobiledevice.LibUSBMuxVersion,
+	}
+}
+
+// parseUSBMuxAddress parses a usbmuxd address of the form "unix:/path/to/socket", "tcp:host:port", or "host:port",
+// and returns network and address to dial. An empty address selects the platform's default.
+func parseUSBMuxAddress(s string) (string, string, error) {
+	switch {
+	case s == "":
+		// Platform default
+		if runtime.GOOS == "windows" {
+			return "tcp", "127.0.0.1:27015", nil
+		}
+
+		return "unix", "/var/run/usbmuxd", nil
+
+	case strings.HasPrefix(strings.ToLower(s), "unix:"):
+		// Unix socket
+		return "unix", s[len("unix:"):], nil
+
+	case strings.HasPrefix(strings.ToLower(s), "tcp:"):
+		// TCP
+		s = s[len("tcp:"):]
+
+	case strings.HasPrefix(s, "/"):
+		// Unix socket, without prefix
+		return "unix", s, nil
+	}
+
+	// TCP
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %w", s, err)
+	}
+
+	return "tcp", s, nil
+}
+
+// usbmuxResultError is returned if usbmuxd responded with a result code other than "ok".
+type usbmuxResultError libimobiledevice.ReplyCode
+
+// Error ...
+func (e usbmuxResultError) Error() string {
+	return "usbmuxd: " + libimobiledevice.ReplyCode(e).String()
+}
+
+// Is classifies the result code: "bad device" means the device is gone, "connection refused" that the service port
+// isn't open.
+func (e usbmuxResultError) Is(target error) bool {
+	code := libimobiledevice.ReplyCode(e)
+
+	return ((code == libimobiledevice.ReplyCodeBadDevice) && (target == ErrDeviceNotFound)) ||
+		((code == libimobiledevice.ReplyCodeConnectionRefused) && (target == ErrServiceUnavailable))
+}
@@ -0,0 +1,187 @@
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/crissyfield/powerhouse/internal/budget"
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// TestSuites is the root element of a JUnit XML file.
+type TestSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     float64     `xml:"time,attr"`
+	Suites   []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite contains the test cases of a recording.
+type TestSuite struct {
+	Name       string     `xml:"name,attr"`
+	Tests      int        `xml:"tests,attr"`
+	Failures   int        `xml:"failures,attr"`
+	Time       float64    `xml:"time,attr"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	TestCases  []TestCase `xml:"testcase"`
+}
+
+// TestCase is a single budget check or phase.
+type TestCase struct {
+	Name       string     `xml:"name,attr"`
+	Classname  string     `xml:"classname,attr"`
+	Time       float64    `xml:"time,attr"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Failure    *Failure   `xml:"failure,omitempty"`
+	SystemOut  string     `xml:"system-out,omitempty"`
+}
+
+// Failure describes why a test case failed.
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Property is a named value of a test suite or test case.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// NewTestSuite creates a test suite for a recording, with a test case for every budget check and every phase.
+func NewTestSuite(name string, rec *powerhouse.Recording, results []budget.Result) TestSuite {
+	s := rec.Summary()
+
+	ts := TestSuite{
+		Name:      name,
+		Time:      s.Duration,
+		Timestamp: s.Start.Format("2006-01-02T15:04:05"),
+		Properties: []Property{
+			property("energy_mWh", s.Energy*1000.0),
+			property("average_power_W", s.AveragePower),
+			property("peak_power_W", s.PeakPower),
+			property("average_current_A", s.AverageCurrent),
+			property("samples", float64(s.Samples)),
+		},
+	}
+
+	if rec.Device != nil {
+		ts.Properties = append(ts.Properties,
+			Property{Name: "device_name", Value: rec.Device.Name},
+			Property{Name: "device_type", Value: rec.Device.Type},
+			Property{Name: "device_udid", Value: rec.Device.UDID},
+			Property{Name: "os_version", Value: rec.Device.OSVersion},
+		)
+	}
+
+	// Budget checks
+	for _, r := range results {
+		tc := TestCase{
+			Name:      "budget " + r.Budget.String(),
+			Classname: name + ".budgets",
+			Properties: []Property{
+				property("threshold_"+r.Budget.Unit(), r.Budget.Max),
+			},
+			SystemOut: r.Message(),
+		}
+
+		if !r.Missing {
+			tc.Properties = append(tc.Properties, property("measured_"+r.Budget.Unit(), r.Value))
+		}
+
+		if !r.Passed() {
+			tc.Failure = &Failure{Message: r.Message(), Type: "budget", Text: r.Message()}
+		}
+
+		ts.add(tc)
+	}
+
+	// Crashes
+	for _, cs := range s.Crashes {
+		msg := fmt.Sprintf("%s crashed at %s (%s), the recording is invalid", cs.Process,
+			cs.Time.Format("2006-01-02T15:04:05"), cs.File)
+
+		ts.add(TestCase{
+			Name:      "crash " + cs.Process,
+			Classname: name + ".crashes",
+			SystemOut: msg,
+			Failure:   &Failure{Message: msg, Type: "crash", Text: msg},
+		})
+	}
+
+	// Phases
+	for _, ps := range s.Phases {
+		tc := TestCase{
+			Name:      "phase " + ps.Label,
+			Classname: name + ".phases",
+			Time:      ps.Duration,
+			Properties: []Property{
+				property("energy_mWh", ps.Energy*1000.0),
+				property("average_power_W", ps.AveragePower),
+				property("samples", float64(ps.Samples)),
+			},
+			SystemOut: fmt.Sprintf("phase %q: %.1f s, %.3f mWh, %.3f W average, %d samples", ps.Label, ps.Duration,
+				ps.Energy*1000.0, ps.AveragePower, ps.Samples),
+		}
+
+		switch {
+		case ps.Samples == 0:
+			msg := fmt.Sprintf("phase %q contains no samples", ps.Label)
+			tc.Failure = &Failure{Message: msg, Type: "phase", Text: msg}
+
+		case ps.Invalid:
+			msg := fmt.Sprintf("phase %q ended after a process crashed", ps.Label)
+			tc.Failure = &Failure{Message: msg, Type: "crash", Text: msg}
+		}
+
+		ts.add(tc)
+	}
+
+	return ts
+}
+
+// add adds a test case and updates the counters.
+func (ts *TestSuite) add(tc TestCase) {
+	ts.TestCases = append(ts.TestCases, tc)
+	ts.Tests++
+
+	if tc.Failure != nil {
+		ts.Failures++
+	}
+}
+
+// WriteFile writes the test suites to a JUnit XML file.
+func WriteFile(path string, suites ...TestSuite) error {
+	root := TestSuites{Name: "powerhouse", Suites: suites}
+
+	for _, ts := range suites {
+		root.Tests += ts.Tests
+		root.Failures += ts.Failures
+		root.Time += ts.Time
+	}
+
+	// Encode
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode JUnit XML: %w", err)
+	}
+
+	// Write
+	err = os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644) //nolint
+	if err != nil {
+		return fmt.Errorf("write JUnit XML: %w", err)
+	}
+
+	return nil
+}
+
+// property creates a numeric property.
+func property(name string, v float64) Property {
+	return Property{Name: name, Value: strconv.FormatFloat(v, 'f', -1, 64)}
+}
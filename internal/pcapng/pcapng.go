@@ -0,0 +1,119 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Block types, options and link type.
+const (
+	blockSectionHeader     = 0x0a0d0d0a
+	blockInterfaceDesc     = 0x00000001
+	blockEnhancedPacket    = 0x00000006
+	byteOrderMagic         = 0x1a2b3c4d
+	linkTypeEthernet       = 1
+	optionEndOfOptions     = 0
+	optionComment          = 1
+	optionSectionUserAppl  = 4
+	optionInterfaceName    = 2
+	optionInterfaceTSResol = 9
+)
+
+// Writer writes packets of a single Ethernet interface as pcapng file, which allows for a comment per packet. Times
+// are written with a resolution of 1 µs.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the file header to w, and returns a writer for the packets.
+func NewWriter(w io.Writer, application string, iface string) (*Writer, error) {
+	// Section header
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1)
+	binary.LittleEndian.PutUint16(shb[6:8], 0)
+	binary.LittleEndian.PutUint64(shb[8:16], 0xffffffffffffffff) // Unknown section length
+
+	shb = appendOption(shb, optionSectionUserAppl, []byte(application))
+	shb = appendOption(shb, optionEndOfOptions, nil)
+
+	if err := writeBlock(w, blockSectionHeader, shb); err != nil {
+		return nil, fmt.Errorf("write section header: %w", err)
+	}
+
+	// Interface description
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:2], linkTypeEthernet)
+	binary.LittleEndian.PutUint32(idb[4:8], 0) // No snapshot length
+
+	idb = appendOption(idb, optionInterfaceName, []byte(iface))
+	idb = appendOption(idb, optionInterfaceTSResol, []byte{6})
+	idb = appendOption(idb, optionEndOfOptions, nil)
+
+	if err := writeBlock(w, blockInterfaceDesc, idb); err != nil {
+		return nil, fmt.Errorf("write interface description: %w", err)
+	}
+
+	return &Writer{w: w}, nil
+}
+
+// WritePacket writes a captured frame, which was origLen bytes long on the wire, with an optional comment.
+func (pw *Writer) WritePacket(t time.Time, frame []byte, origLen int, comment string) error {
+	ts := uint64(t.UnixMicro())
+
+	epb := make([]byte, 20, 20+len(frame)+len(comment)+16)
+	binary.LittleEndian.PutUint32(epb[0:4], 0) // Interface ID
+	binary.LittleEndian.PutUint32(epb[4:8], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:12], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:16], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(epb[16:20], uint32(max(origLen, len(frame))))
+
+	epb = append(epb, frame...)
+	epb = pad(epb)
+
+	if comment != "" {
+		epb = appendOption(epb, optionComment, []byte(comment))
+		epb = appendOption(epb, optionEndOfOptions, nil)
+	}
+
+	if err := writeBlock(pw.w, blockEnhancedPacket, epb); err != nil {
+		return fmt.Errorf("write packet: %w", err)
+	}
+
+	return nil
+}
+
+// writeBlock writes a block with the given type and body, which must be padded to 32 bits.
+func writeBlock(w io.Writer, typ uint32, body []byte) error {
+	length := uint32(12 + len(body))
+
+	block := make([]byte, 0, length)
+	block = binary.LittleEndian.AppendUint32(block, typ)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, length)
+
+	_, err := w.Write(block)
+
+	return err
+}
+
+// appendOption appends an option, padded to 32 bits.
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+
+	return pad(b)
+}
+
+// pad pads to 32 bits.
+func pad(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+
+	return b
+}
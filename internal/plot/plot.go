@@ -0,0 +1,450 @@
+package plot
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// Layout of a chart (in px).
+const (
+	marginLeft   = 64.0
+	marginRight  = 24.0
+	marginTop    = 36.0
+	marginBottom = 28.0
+	panelGap     = 28.0
+)
+
+// Colors of a chart.
+var (
+	colorBackground = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	colorText       = color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	colorAxis       = color.NRGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff}
+	colorGrid       = color.NRGBA{R: 0xe5, G: 0xe5, B: 0xe5, A: 0xff}
+)
+
+// palette contains the colors of overlaid recordings.
+var palette = []color.NRGBA{
+	{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff},
+	{R: 0xd6, G: 0x27, B: 0x28, A: 0xff},
+	{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff},
+	{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff},
+	{R: 0x94, G: 0x67, B: 0xbd, A: 0xff},
+	{R: 0x8c, G: 0x56, B: 0x4b, A: 0xff},
+}
+
+// Metric is a time series that can be drawn from the samples of a recording.
+type Metric struct {
+	// Name of the metric, as used on the command line.
+	Name string
+
+	// Title of the chart panel, including the unit.
+	Title string
+
+	// Value returns the value of the metric for a sample, and false if the sample doesn't contain it.
+	Value func(m *powerhouse.Metrics) (float64, bool)
+}
+
+// Metrics lists all metrics that can be drawn. Battery and backlight metrics come first, in their default order,
+// followed by the metrics of optional collectors.
+var Metrics = []Metric{
+	{
+		Name:  "power",
+		Title: "Power (W)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Battery == nil {
+				return 0, false
+			}
+
+			return m.Battery.Power(), true
+		},
+	},
+	{
+		Name:  "current",
+		Title: "Current (A)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Battery == nil {
+				return 0, false
+			}
+
+			return -m.Battery.InstantAmperage, true
+		},
+	},
+	{
+		Name:  "voltage",
+		Title: "Voltage (V)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Battery == nil {
+				return 0, false
+			}
+
+			return m.Battery.Voltage, true
+		},
+	},
+	{
+		Name:  "capacity",
+		Title: "Capacity (%)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Battery == nil {
+				return 0, false
+			}
+
+			return float64(m.Battery.CurrentCapacity), true
+		},
+	},
+	{
+		Name:  "temperature",
+		Title: "Temperature (°C)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Battery == nil {
+				return 0, false
+			}
+
+			return m.Battery.Temperature, true
+		},
+	},
+	{
+		Name:  "brightness",
+		Title: "Brightness",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Backlight == nil {
+				return 0, false
+			}
+
+			return float64(m.Backlight.BrightnessValue), true
+		},
+	},
+	{
+		Name:  "cpu",
+		Title: "CPU load (%)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.CPU == nil {
+				return 0, false
+			}
+
+			return m.CPU.Load(), true
+		},
+	},
+	{
+		Name:  "gpu",
+		Title: "GPU utilization (%)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.GPU == nil {
+				return 0, false
+			}
+
+			return m.GPU.DeviceUtilization, true
+		},
+	},
+	{
+		Name:  "memory",
+		Title: "Memory pressure (%)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if m.Memory == nil {
+				return 0, false
+			}
+
+			return m.Memory.Pressure(), true
+		},
+	},
+	{
+		Name:  "network",
+		Title: "Network traffic (KB/s)",
+		Value: func(m *powerhouse.Metrics) (float64, bool) {
+			if (m.Network == nil) || (m.Network.Interval <= 0) {
+				return 0, false
+			}
+
+			return float64(m.Network.BytesIn+m.Network.BytesOut) / 1000.0 / m.Network.Interval, true
+		},
+	},
+}
+
+// MetricByName returns the metric with the given name.
+func MetricByName(name string) (Metric, error) {
+	for _, m := range Metrics {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+
+	return Metric{}, fmt.Errorf("unknown metric %q", name)
+}
+
+// Series is a recording drawn into a chart.
+type Series struct {
+	// Label of the series in the legend.
+	Label string
+
+	// Recording to draw.
+	Recording *powerhouse.Recording
+}
+
+// Chart draws metrics of one or more recordings as stacked time-series panels. Recordings are overlaid, aligned at
+// their start time, and phases defined by their markers are shaded.
+type Chart struct {
+	// Series to draw.
+	Series []Series
+
+	// Metrics to draw, one panel each.
+	Metrics []Metric
+
+	// Size of the chart (in px).
+	Width  int
+	Height int
+}
+
+// anchor aligns text horizontally.
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// point is a position on a canvas.
+type point struct {
+	X, Y float64
+}
+
+// canvas is the drawing backend of a chart.
+type canvas interface {
+	// Rect fills a rectangle.
+	Rect(x, y, w, h float64, fill color.NRGBA)
+
+	// Line draws a straight line, optionally dashed.
+	Line(x1, y1, x2, y2 float64, stroke color.NRGBA, width float64, dashed bool)
+
+	// Polyline draws connected line segments.
+	Polyline(pts []point, stroke color.NRGBA, width float64)
+
+	// Text draws text with its baseline at y.
+	Text(x, y float64, s string, fill color.NRGBA, a anchor)
+}
+
+// draw draws the chart onto the canvas.
+func (c *Chart) draw(cv canvas) {
+	width, height := float64(c.Width), float64(c.Height)
+
+	// Background
+	cv.Rect(0, 0, width, height, colorBackground)
+
+	// Legend
+	x := marginLeft
+
+	for i, s := range c.Series {
+		col := palette[i%len(palette)]
+
+		cv.Rect(x, 14, 12, 12, col)
+		cv.Text(x+18, 24, s.Label, colorText, anchorStart)
+
+		x += 18 + 7*float64(len([]rune(s.Label))) + 24
+	}
+
+	// Time axis, shared by all panels
+	var duration float64
+
+	for _, s := range c.Series {
+		duration = math.Max(duration, s.Recording.Summary().Duration)
+	}
+
+	if duration <= 0 {
+		duration = 1
+	}
+
+	plotLeft, plotRight := marginLeft, width-marginRight
+	plotWidth := plotRight - plotLeft
+
+	// Samples and markers before the start (e.g. the initial battery sample, which carries the time the battery was
+	// last updated) or after the end are drawn at the edges of the plot
+	toX := func(t float64) float64 {
+		return plotLeft + math.Max(0, math.Min(t/duration, 1))*plotWidth
+	}
+
+	timeTicks := elapsedTicks(duration)
+
+	// Panels
+	n := float64(len(c.Metrics))
+	panelHeight := (height - marginTop - marginBottom - n*panelGap) / n
+
+	for i, metric := range c.Metrics {
+		top := marginTop + panelGap + float64(i)*(panelHeight+panelGap)
+		bottom := top + panelHeight
+
+		// Value range
+		lo, hi := math.Inf(1), math.Inf(-1)
+
+		for _, s := range c.Series {
+			for _, m := range s.Recording.Samples {
+				if v, ok := metric.Value(m); ok {
+					lo, hi = math.Min(lo, v), math.Max(hi, v)
+				}
+			}
+		}
+
+		if math.IsInf(lo, 0) {
+			lo, hi = 0, 1
+		}
+
+		if hi-lo < 1e-9 {
+			lo, hi = lo-0.5, hi+0.5
+		}
+
+		pad := (hi - lo) * 0.05
+		lo, hi = lo-pad, hi+pad
+
+		toY := func(v float64) float64 {
+			return bottom - (v-lo)/(hi-lo)*panelHeight
+		}
+
+		// Title
+		cv.Text(plotLeft, top-8, metric.Title, colorText, anchorStart)
+
+		// Phases
+		for j, s := range c.Series {
+			shade := palette[j%len(palette)]
+			shade.A = 0x24
+
+			for _, ph := range s.Recording.Phases() {
+				x0 := toX(ph.Start.Sub(s.Recording.Start).Seconds())
+				x1 := toX(ph.End.Sub(s.Recording.Start).Seconds())
+
+				if x1 <= x0 {
+					continue
+				}
+
+				cv.Rect(x0, top, x1-x0, panelHeight, shade)
+
+				if i == 0 {
+					cv.Text(x0+3, top+12, ph.Label, colorText, anchorStart)
+				}
+			}
+		}
+
+		// Grid and value axis
+		for _, v := range ticks(lo, hi) {
+			y := toY(v)
+
+			cv.Line(plotLeft, y, plotRight, y, colorGrid, 1, false)
+			cv.Text(plotLeft-6, y+4, formatValue(v), colorText, anchorEnd)
+		}
+
+		for _, t := range timeTicks {
+			cv.Line(toX(t), top, toX(t), bottom, colorGrid, 1, false)
+		}
+
+		// Event markers
+		for j, s := range c.Series {
+			for _, mk := range s.Recording.Markers {
+				// Skip events outside the time axis
+				t := mk.Time.Sub(s.Recording.Start).Seconds()
+				if (mk.Type != powerhouse.MarkerTypeEvent) || (t < 0) || (t > duration) {
+					continue
+				}
+
+				mx := toX(t)
+				cv.Line(mx, top, mx, bottom, palette[j%len(palette)], 1, true)
+			}
+		}
+
+		// Series
+		for j, s := range c.Series {
+			var pts []point
+
+			for _, m := range s.Recording.Samples {
+				v, ok := metric.Value(m)
+				if !ok {
+					continue
+				}
+
+				pts = append(pts, point{X: toX(m.Time().Sub(s.Recording.Start).Seconds()), Y: toY(v)})
+			}
+
+			cv.Polyline(pts, palette[j%len(palette)], 1.5)
+		}
+
+		// Frame
+		cv.Line(plotLeft, bottom, plotRight, bottom, colorAxis, 1, false)
+		cv.Line(plotLeft, top, plotLeft, bottom, colorAxis, 1, false)
+	}
+
+	// Time axis labels
+	for _, t := range timeTicks {
+		cv.Text(toX(t), height-marginBottom+16, formatElapsed(t), colorText, anchorMiddle)
+	}
+}
+
+// ticks returns "nice" tick values between lo and hi.
+func ticks(lo float64, hi float64) []float64 {
+	// Step of 1, 2 or 5 times a power of ten, for about 5 ticks
+	raw := (hi - lo) / 5
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+
+	step := 10 * mag
+
+	for _, f := range []float64{1, 2, 5} {
+		if raw <= f*mag {
+			step = f * mag
+			break
+		}
+	}
+
+	// Ticks
+	var ts []float64
+
+	for v := math.Ceil(lo/step) * step; v <= hi+step*1e-9; v += step {
+		ts = append(ts, v)
+	}
+
+	return ts
+}
+
+// timeSteps are the steps between ticks of the time axis (in s).
+var timeSteps = []float64{1, 2, 5, 10, 15, 30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 10800, 21600}
+
+// elapsedTicks returns tick values for a time axis from 0 to duration (in s), aligned to full seconds, minutes or
+// hours.
+func elapsedTicks(duration float64) []float64 {
+	// Smallest step for at most 8 ticks
+	step := timeSteps[len(timeSteps)-1]
+
+	for _, s := range timeSteps {
+		if duration/s <= 8 {
+			step = s
+			break
+		}
+	}
+
+	// Ticks
+	var ts []float64
+
+	for t := 0.0; t <= duration; t += step {
+		ts = append(ts, t)
+	}
+
+	return ts
+}
+
+// formatValue formats an axis value with as few digits as needed.
+func formatValue(v float64) string {
+	if math.Abs(v) < 1e-9 {
+		return "0"
+	}
+
+	return fmt.Sprintf("%.4g", v)
+}
+
+// formatElapsed formats elapsed seconds as "m:ss" or "h:mm:ss".
+func formatElapsed(s float64) string {
+	d := time.Duration(s * float64(time.Second)).Round(time.Second)
+
+	if d >= time.Hour {
+		return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
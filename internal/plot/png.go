@@ -0,0 +1,112 @@
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// asciiReplacer replaces non-ASCII characters used in charts.
+var asciiReplacer = strings.NewReplacer("°", "")
+
+// WritePNG writes the chart as PNG image.
+func (c *Chart) WritePNG(w io.Writer) error {
+	cv := &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))}
+
+	// Draw
+	c.draw(cv)
+
+	// Encode
+	err := png.Encode(w, cv.img)
+	if err != nil {
+		return fmt.Errorf("encode PNG: %w", err)
+	}
+
+	return nil
+}
+
+// pngCanvas draws into an RGBA image.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+// Rect fills a rectangle.
+func (cv *pngCanvas) Rect(x, y, w, h float64, fill color.NRGBA) {
+	r := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h)))
+	draw.Draw(cv.img, r, image.NewUniform(fill), image.Point{}, draw.Over)
+}
+
+// Line draws a straight line, optionally dashed.
+func (cv *pngCanvas) Line(x1, y1, x2, y2 float64, stroke color.NRGBA, width float64, dashed bool) {
+	length := math.Hypot(x2-x1, y2-y1)
+
+	// Step along the line in half pixels, stamping a dot of the line's width
+	for d := 0.0; d <= length; d += 0.5 {
+		if dashed && (math.Mod(d, 7) >= 4) {
+			continue
+		}
+
+		t := 0.0
+		if length > 0 {
+			t = d / length
+		}
+
+		cv.dot(x1+t*(x2-x1), y1+t*(y2-y1), width, stroke)
+	}
+}
+
+// Polyline draws connected line segments.
+func (cv *pngCanvas) Polyline(pts []point, stroke color.NRGBA, width float64) {
+	for i := 1; i < len(pts); i++ {
+		cv.Line(pts[i-1].X, pts[i-1].Y, pts[i].X, pts[i].Y, stroke, width, false)
+	}
+}
+
+// Text draws text with its baseline at y.
+func (cv *pngCanvas) Text(x, y float64, s string, fill color.NRGBA, a anchor) {
+	// The built-in font only covers ASCII
+	s = asciiReplacer.Replace(s)
+
+	d := &font.Drawer{
+		Dst:  cv.img,
+		Src:  image.NewUniform(fill),
+		Face: basicfont.Face7x13,
+	}
+
+	// Align
+	w := float64(d.MeasureString(s).Round())
+
+	switch a {
+	case anchorStart:
+	case anchorMiddle:
+		x -= w / 2
+	case anchorEnd:
+		x -= w
+	}
+
+	d.Dot = fixed.P(int(math.Round(x)), int(math.Round(y)))
+	d.DrawString(s)
+}
+
+// dot sets all pixels within a square of the given width around (x, y). Colors are not blended, so overlapping dots
+// of translucent colors don't add up.
+func (cv *pngCanvas) dot(x, y, width float64, c color.NRGBA) {
+	r := math.Max(width/2, 0.5)
+
+	for py := int(math.Floor(y - r + 0.5)); py < int(math.Floor(y+r+0.5)); py++ {
+		for px := int(math.Floor(x - r + 0.5)); px < int(math.Floor(x+r+0.5)); px++ {
+			if (image.Point{X: px, Y: py}).In(cv.img.Rect) {
+				cv.img.Set(px, py, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package plot
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// WriteSVG writes the chart as SVG image.
+func (c *Chart) WriteSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	cv := &svgCanvas{w: bw}
+
+	// Draw
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`font-family="sans-serif" font-size="12">`+"\n", c.Width, c.Height, c.Width, c.Height)
+
+	c.draw(cv)
+
+	fmt.Fprintln(bw, "</svg>")
+
+	// Flush
+	err := bw.Flush()
+	if err != nil {
+		return fmt.Errorf("write SVG: %w", err)
+	}
+
+	return nil
+}
+
+// svgCanvas draws into an SVG document.
+type svgCanvas struct {
+	w io.Writer
+}
+
+// Rect fills a rectangle.
+func (cv *svgCanvas) Rect(x, y, w, h float64, fill color.NRGBA) {
+	fmt.Fprintf(cv.w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"%s/>`+"\n",
+		x, y, w, h, svgColor(fill), svgOpacity("fill-opacity", fill))
+}
+
+// Line draws a straight line, optionally dashed.
+func (cv *svgCanvas) Line(x1, y1, x2, y2 float64, stroke color.NRGBA, width float64, dashed bool) {
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="4 3"`
+	}
+
+	fmt.Fprintf(cv.w, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%.1f"%s%s/>`+"\n",
+		x1, y1, x2, y2, svgColor(stroke), width, svgOpacity("stroke-opacity", stroke), dash)
+}
+
+// Polyline draws connected line segments.
+func (cv *svgCanvas) Polyline(pts []point, stroke color.NRGBA, width float64) {
+	if len(pts) == 0 {
+		return
+	}
+
+	coords := make([]string, len(pts))
+	for i, p := range pts {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p.X, p.Y)
+	}
+
+	fmt.Fprintf(cv.w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%.1f" `+
+		`stroke-linejoin="round"/>`+"\n", strings.Join(coords, " "), svgColor(stroke), width)
+}
+
+// Text draws text with its baseline at y.
+func (cv *svgCanvas) Text(x, y float64, s string, fill color.NRGBA, a anchor) {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+
+	fmt.Fprintf(cv.w, `<text x="%.1f" y="%.1f" fill="%s" text-anchor="%s">%s</text>`+"\n",
+		x, y, svgColor(fill), [...]string{"start", "middle", "end"}[a], sb.String())
+}
+
+// svgColor formats a color as SVG color, without alpha.
+func svgColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// svgOpacity formats the alpha of a color as SVG opacity attribute, or returns an empty string for opaque colors.
+func svgOpacity(attr string, c color.NRGBA) string {
+	if c.A == 0xff {
+		return ""
+	}
+
+	return fmt.Sprintf(` %s="%.2f"`, attr, float64(c.A)/255.0)
+}
@@ -0,0 +1,264 @@
+// Powerhouse report. Draws interactive charts of the recordings in REPORT.
+"use strict";
+
+// Metrics drawn, by index into the samples
+const METRICS = [
+  { index: 1, title: "Power", unit: "W", digits: 3 },
+  { index: 2, title: "Current", unit: "A", digits: 3 },
+  { index: 3, title: "Voltage", unit: "V", digits: 3 },
+  { index: 4, title: "Capacity", unit: "%", digits: 0 },
+  { index: 5, title: "Temperature", unit: "°C", digits: 1 },
+  { index: 6, title: "Brightness", unit: "", digits: 0 },
+];
+
+// Colors of the recordings, the baseline is drawn in gray
+const PALETTE = ["#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"];
+const BASELINE = "#8e8e93";
+
+const PAD_LEFT = 50, PAD_RIGHT = 10, PAD_TOP = 8, PAD_BOTTOM = 20;
+
+// color returns the color of the i-th recording.
+function color(i) {
+  return REPORT[i].Baseline ? BASELINE : PALETTE[i % PALETTE.length];
+}
+
+// formatElapsed formats seconds as "m:ss".
+function formatElapsed(s) {
+  s = Math.round(s);
+  return Math.floor(s / 60) + ":" + String(s % 60).padStart(2, "0");
+}
+
+// nearest returns the sample of a recording closest to the offset t.
+function nearest(samples, t) {
+  let best = null;
+  for (const s of samples) {
+    if (!best || Math.abs(s[0] - t) < Math.abs(best[0] - t)) {
+      best = s;
+    }
+  }
+  return best;
+}
+
+// createChart creates the chart of a metric and returns a function drawing it, with an optional hover offset.
+function createChart(container, metric, duration) {
+  const el = document.createElement("div");
+  el.className = "chart";
+
+  const title = document.createElement("div");
+  title.className = "title";
+  title.textContent = metric.title + (metric.unit ? ` (${metric.unit})` : "");
+
+  const canvas = document.createElement("canvas");
+  const tooltip = document.createElement("div");
+  tooltip.className = "tooltip";
+  tooltip.hidden = true;
+
+  el.append(title, canvas, tooltip);
+  container.append(el);
+
+  // Value range
+  let v0 = Infinity, v1 = -Infinity;
+  for (const rec of REPORT) {
+    for (const s of rec.Samples || []) {
+      v0 = Math.min(v0, s[metric.index]);
+      v1 = Math.max(v1, s[metric.index]);
+    }
+  }
+  if (!isFinite(v0)) {
+    v0 = 0;
+    v1 = 1;
+  }
+  if (v1 - v0 < 1e-9) {
+    v0 -= 0.5;
+    v1 += 0.5;
+  }
+
+  const draw = (hover) => {
+    const ratio = window.devicePixelRatio || 1;
+    const w = canvas.clientWidth, h = canvas.clientHeight;
+    canvas.width = w * ratio;
+    canvas.height = h * ratio;
+
+    const ctx = canvas.getContext("2d");
+    ctx.scale(ratio, ratio);
+
+    const x = (t) => PAD_LEFT + t / duration * (w - PAD_LEFT - PAD_RIGHT);
+    const y = (v) => h - PAD_BOTTOM - (v - v0) / (v1 - v0) * (h - PAD_TOP - PAD_BOTTOM);
+
+    // Phases
+    REPORT.forEach((rec, i) => {
+      ctx.fillStyle = color(i) + "22";
+      for (const ph of rec.Phases || []) {
+        ctx.fillRect(x(ph.Start), PAD_TOP, x(ph.End) - x(ph.Start), h - PAD_TOP - PAD_BOTTOM);
+      }
+    });
+
+    // Axes
+    ctx.fillStyle = "#6e6e73";
+    ctx.strokeStyle = "#d2d2d7";
+    ctx.font = "10px sans-serif";
+    ctx.textAlign = "right";
+    ctx.fillText(v1.toFixed(metric.digits), PAD_LEFT - 4, y(v1) + 8);
+    ctx.fillText(v0.toFixed(metric.digits), PAD_LEFT - 4, y(v0));
+    ctx.textAlign = "center";
+    for (let i = 0; i <= 5; i++) {
+      ctx.fillText(formatElapsed(duration * i / 5), x(duration * i / 5), h - 6);
+    }
+    ctx.strokeRect(PAD_LEFT, PAD_TOP, w - PAD_LEFT - PAD_RIGHT, h - PAD_TOP - PAD_BOTTOM);
+
+    // Event markers
+    ctx.setLineDash([3, 3]);
+    REPORT.forEach((rec, i) => {
+      ctx.strokeStyle = color(i);
+      for (const m of rec.Markers || []) {
+        ctx.beginPath();
+        ctx.moveTo(x(m.Start), PAD_TOP);
+        ctx.lineTo(x(m.Start), h - PAD_BOTTOM);
+        ctx.stroke();
+      }
+    });
+    ctx.setLineDash([]);
+
+    // Lines
+    ctx.lineWidth = 1.5;
+    REPORT.forEach((rec, i) => {
+      ctx.strokeStyle = color(i);
+      ctx.beginPath();
+      (rec.Samples || []).forEach((s, j) => {
+        const px = x(s[0]), py = y(s[metric.index]);
+        j === 0 ? ctx.moveTo(px, py) : ctx.lineTo(px, py);
+      });
+      ctx.stroke();
+    });
+    ctx.lineWidth = 1;
+
+    // Hover
+    if (hover === undefined) {
+      tooltip.hidden = true;
+      return;
+    }
+
+    ctx.strokeStyle = "#1d1d1f";
+    ctx.beginPath();
+    ctx.moveTo(x(hover), PAD_TOP);
+    ctx.lineTo(x(hover), h - PAD_BOTTOM);
+    ctx.stroke();
+
+    const lines = [formatElapsed(hover)];
+    REPORT.forEach((rec, i) => {
+      const s = nearest(rec.Samples || [], hover);
+      if (s) {
+        lines.push(`<span style="color:${color(i)}">■</span> ${escape(rec.Label)}: ` +
+          `${s[metric.index].toFixed(metric.digits)} ${metric.unit}`);
+      }
+      for (const ph of rec.Phases || []) {
+        if (hover >= ph.Start && hover <= ph.End) {
+          lines.push(`&nbsp;&nbsp;phase ${escape(ph.Label)}`);
+        }
+      }
+    });
+
+    tooltip.innerHTML = lines.join("<br>");
+    tooltip.hidden = false;
+    tooltip.style.left = Math.min(x(hover) + 10, w - tooltip.offsetWidth) + "px";
+  };
+
+  // offset converts a mouse event to an offset (in s)
+  const offset = (ev) => {
+    const w = canvas.clientWidth;
+    const t = (ev.offsetX - PAD_LEFT) / (w - PAD_LEFT - PAD_RIGHT) * duration;
+    return Math.max(0, Math.min(duration, t));
+  };
+
+  canvas.addEventListener("mousemove", (ev) => hover(offset(ev)));
+  canvas.addEventListener("mouseleave", () => hover());
+
+  return draw;
+}
+
+// createScreenshots creates the panel of screenshots and returns a function showing those nearest to a hover offset.
+function createScreenshots(container) {
+  const panel = document.createElement("div");
+  panel.className = "screenshots";
+  panel.hidden = true;
+  container.append(panel);
+
+  const figures = REPORT.map((rec, i) => {
+    if (!rec.Screenshots) {
+      return null;
+    }
+
+    const figure = document.createElement("figure");
+    const img = document.createElement("img");
+    const caption = document.createElement("figcaption");
+    caption.style.color = color(i);
+    figure.append(img, caption);
+    panel.append(figure);
+
+    return { figure, img, caption };
+  });
+
+  return (hover) => {
+    panel.hidden = hover === undefined || figures.every((f) => !f);
+    if (panel.hidden) {
+      return;
+    }
+
+    REPORT.forEach((rec, i) => {
+      if (!figures[i]) {
+        return;
+      }
+
+      let best = rec.Screenshots[0];
+      for (const s of rec.Screenshots) {
+        if (Math.abs(s.Offset - hover) < Math.abs(best.Offset - hover)) {
+          best = s;
+        }
+      }
+
+      figures[i].img.src = best.Image;
+      figures[i].caption.textContent = `${rec.Label} at ${formatElapsed(best.Offset)}`;
+    });
+  };
+}
+
+// escape escapes text for use in HTML.
+function escape(s) {
+  const div = document.createElement("div");
+  div.textContent = s;
+  return div.innerHTML;
+}
+
+// Legend
+const container = document.getElementById("charts");
+const legend = document.createElement("div");
+legend.className = "legend";
+REPORT.forEach((rec, i) => {
+  const span = document.createElement("span");
+  const swatch = document.createElement("i");
+  swatch.style.background = color(i);
+  span.append(swatch, rec.Label + (rec.Baseline ? " (baseline)" : ""));
+  legend.append(span);
+});
+container.append(legend);
+
+// Charts, sharing the time axis
+let duration = 1;
+for (const rec of REPORT) {
+  for (const s of rec.Samples || []) {
+    duration = Math.max(duration, s[0]);
+  }
+}
+
+const charts = METRICS.map((m) => createChart(container, m, duration));
+charts.forEach((c) => c());
+
+// Screenshots nearest to the hover offset
+const screenshots = createScreenshots(container);
+
+// hover redraws all charts and screenshots for a hover offset, or without one.
+function hover(t) {
+  charts.forEach((c) => c(t));
+  screenshots(t);
+}
+window.addEventListener("resize", () => charts.forEach((c) => c()));
@@ -0,0 +1,621 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"golang.org/x/image/draw"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+// Thresholds of data-quality warnings.
+const (
+	temperatureHigh = 35.0
+	minDuration     = time.Minute
+	gapFactor       = 3.0
+)
+
+// maxProcesses is the number of processes listed per recording.
+const maxProcesses = 15
+
+// Power spikes and the log lines shown around them.
+const (
+	maxSpikes        = 5
+	spikeFactor      = 1.5
+	spikeLinesBefore = 10 * time.Second
+	spikeLinesAfter  = 5 * time.Second
+	maxLinesPerSpike = 20
+)
+
+// Screenshots are embedded as JPEG thumbnails of this width (in px) and quality.
+const (
+	thumbnailWidth   = 240
+	thumbnailQuality = 75
+)
+
+var (
+	//go:embed assets/report.html
+	reportHTML string
+
+	//go:embed assets/report.css
+	reportCSS string
+
+	//go:embed assets/report.js
+	reportJS string
+)
+
+// reportTemplate renders a report.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"mwh":       func(wh float64) string { return fmt.Sprintf("%.1f", wh*1000.0) },
+	"fixed":     func(digits int, v float64) string { return fmt.Sprintf("%.*f", digits, v) },
+	"percent":   func(share float64) string { return fmt.Sprintf("%.1f", share*100.0) },
+	"megabytes": func(b uint64) string { return fmt.Sprintf("%.2f", float64(b)/1e6) },
+	"gigabytes": func(b uint64) string { return fmt.Sprintf("%.1f", float64(b)/1e9) },
+	"elapsed":   formatElapsed,
+	"datetime":  func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+}).Parse(reportHTML))
+
+// Input is a recording included in a report.
+type Input struct {
+	// Label of the recording, e.g. its file name.
+	Label string
+
+	// Recording to include.
+	Recording *powerhouse.Recording
+}
+
+// Report renders recordings as a single, self-contained HTML file.
+type Report struct {
+	// Title of the report.
+	Title string
+
+	// Recordings to include.
+	Inputs []Input
+
+	// Baseline all recordings are compared against, or nil.
+	Baseline *Input
+}
+
+// reportData is passed to the report template.
+type reportData struct {
+	Title      string
+	Generated  time.Time
+	Recordings []*recordingData
+	Baseline   *recordingData
+	Charts     []chartSeries
+	CSS        template.CSS
+	Script     template.JS
+}
+
+// recordingData describes a recording in the report template.
+type recordingData struct {
+	Label        string
+	IsBaseline   bool
+	Device       *powerhouse.Device
+	Metadata     powerhouse.Metadata
+	Battery      *powerhouse.BatteryMetrics
+	Summary      *powerhouse.Summary
+	Warnings     []string
+	Comparison   []comparisonRow
+	StateChanges []powerhouse.StateChange
+	Phases       []phaseRow
+	Processes    []powerhouse.ProcessSummary
+	Shares       *shares
+	Spikes       []spikeRow
+	Bursts       []burstRow
+}
+
+// burstRow describes a traffic burst in the report template.
+type burstRow struct {
+	powerhouse.TrafficBurst
+	Offset   time.Duration
+	Duration float64
+}
+
+// spikeRow describes a power spike and the device log lines around it.
+type spikeRow struct {
+	Offset time.Duration
+	Power  float64
+	Lines  []logRow
+}
+
+// logRow describes a device log line relative to a spike.
+type logRow struct {
+	powerhouse.LogLine
+	Delta string
+}
+
+// shares splits the estimated energy of all processes between the app under test and the background.
+type shares struct {
+	App        float64
+	Background float64
+}
+
+// comparisonRow compares a statistic of a recording with the baseline.
+type comparisonRow struct {
+	Name     string
+	Value    string
+	Baseline string
+	Delta    string
+	Worse    bool
+}
+
+// phaseRow describes a phase in the report template, optionally compared with the same phase of the baseline.
+type phaseRow struct {
+	powerhouse.PhaseSummary
+	Offset      time.Duration
+	HasBaseline bool
+	Baseline    powerhouse.PhaseSummary
+	Delta       string
+	Worse       bool
+}
+
+// chartSeries is the data of a recording drawn by the interactive charts.
+type chartSeries struct {
+	Label    string
+	Baseline bool
+
+	// Samples as [offset (in s), power, current, voltage, capacity, temperature, brightness]
+	Samples [][7]float64
+
+	// Phases and event markers, as offsets (in s)
+	Phases  []chartPhase
+	Markers []chartPhase
+
+	// Screenshots as offsets (in s) and thumbnails
+	Screenshots []chartScreenshot
+}
+
+// chartPhase is a phase or event marker drawn by the interactive charts.
+type chartPhase struct {
+	Label string
+	Start float64
+	End   float64
+}
+
+// chartScreenshot is a screenshot shown when hovering over the interactive charts.
+type chartScreenshot struct {
+	Offset float64
+	Image  string
+}
+
+// Write renders the report as HTML.
+func (r *Report) Write(w io.Writer) error {
+	data := reportData{
+		Title:     r.Title,
+		Generated: time.Now(),
+		CSS:       template.CSS(reportCSS), //nolint
+		Script:    template.JS(reportJS),   //nolint
+	}
+
+	// Baseline
+	var base *powerhouse.Summary
+	var baseState *powerhouse.DeviceState
+
+	if r.Baseline != nil {
+		data.Baseline = newRecordingData(*r.Baseline, nil, nil)
+		data.Baseline.IsBaseline = true
+		base = data.Baseline.Summary
+		baseState = r.Baseline.Recording.Metadata.StartState
+	}
+
+	// Recordings
+	for _, in := range r.Inputs {
+		data.Recordings = append(data.Recordings, newRecordingData(in, base, baseState))
+		data.Charts = append(data.Charts, newChartSeries(in, false))
+	}
+
+	if r.Baseline != nil {
+		data.Charts = append(data.Charts, newChartSeries(*r.Baseline, true))
+	}
+
+	// Render
+	err := reportTemplate.Execute(w, data)
+	if err != nil {
+		return fmt.Errorf("render report: %w", err)
+	}
+
+	return nil
+}
+
+// newRecordingData prepares a recording for the report template, compared with the baseline summary and device state
+// if not nil.
+func newRecordingData(in Input, base *powerhouse.Summary, baseState *powerhouse.DeviceState) *recordingData {
+	rec := in.Recording
+	s := rec.Summary()
+
+	rd := &recordingData{
+		Label:    in.Label,
+		Device:   rec.Device,
+		Metadata: rec.Metadata,
+		Summary:  s,
+		Warnings: Warnings(rec),
+	}
+
+	// Battery details of the first sample
+	for _, m := range rec.Samples {
+		if m.Battery != nil {
+			rd.Battery = m.Battery
+			break
+		}
+	}
+
+	// Phases
+	for _, ps := range s.Phases {
+		row := phaseRow{PhaseSummary: ps, Offset: ps.Start.Sub(s.Start)}
+
+		if base != nil {
+			for _, bps := range base.Phases {
+				if bps.Label == ps.Label {
+					row.HasBaseline = true
+					row.Baseline = bps
+					row.Delta, row.Worse = delta(ps.Energy, bps.Energy)
+
+					break
+				}
+			}
+		}
+
+		rd.Phases = append(rd.Phases, row)
+	}
+
+	// Processes, and the share of the app under test
+	if len(s.Processes) > 0 {
+		rd.Processes = s.Processes[:min(len(s.Processes), maxProcesses)]
+
+		if rec.Metadata.LaunchedApp != nil {
+			rd.Shares = &shares{}
+
+			for _, ps := range s.Processes {
+				if ps.AppUnderTest {
+					rd.Shares.App += ps.Share
+				} else {
+					rd.Shares.Background += ps.Share
+				}
+			}
+		}
+	}
+
+	// Log lines around power spikes
+	rd.Spikes = spikes(rec)
+
+	// Traffic bursts
+	if s.Traffic != nil {
+		for _, b := range s.Traffic.Bursts {
+			rd.Bursts = append(rd.Bursts, burstRow{
+				TrafficBurst: b,
+				Offset:       b.Start.Sub(s.Start),
+				Duration:     b.End.Sub(b.Start).Seconds(),
+			})
+		}
+	}
+
+	// Comparison with baseline
+	if base != nil {
+		row := func(name string, format string, v float64, b float64) comparisonRow {
+			d, worse := delta(v, b)
+			return comparisonRow{
+				Name:     name,
+				Value:    fmt.Sprintf(format, v),
+				Baseline: fmt.Sprintf(format, b),
+				Delta:    d,
+				Worse:    worse,
+			}
+		}
+
+		rd.Comparison = []comparisonRow{
+			row("Energy (mWh)", "%.1f", s.Energy*1000.0, base.Energy*1000.0),
+			row("Average power (W)", "%.3f", s.AveragePower, base.AveragePower),
+			row("Peak power (W)", "%.3f", s.PeakPower, base.PeakPower),
+			row("Average current (A)", "%.3f", s.AverageCurrent, base.AverageCurrent),
+			row("Average brightness", "%.0f", s.AverageBrightness, base.AverageBrightness),
+		}
+	}
+
+	if (baseState != nil) && (rec.Metadata.StartState != nil) {
+		rd.StateChanges = rec.Metadata.StartState.Diff(baseState)
+	}
+
+	return rd
+}
+
+// newChartSeries prepares a recording for the interactive charts.
+func newChartSeries(in Input, baseline bool) chartSeries {
+	rec := in.Recording
+
+	cs := chartSeries{Label: in.Label, Baseline: baseline}
+
+	// Samples
+	for _, m := range rec.Samples {
+		if m.Battery == nil {
+			continue
+		}
+
+		var brightness float64
+		if m.Backlight != nil {
+			brightness = float64(m.Backlight.BrightnessValue)
+		}
+
+		cs.Samples = append(cs.Samples, [7]float64{
+			m.Battery.Time.Sub(rec.Start).Seconds(),
+			m.Battery.Power(),
+			-m.Battery.InstantAmperage,
+			m.Battery.Voltage,
+			float64(m.Battery.CurrentCapacity),
+			m.Battery.Temperature,
+			brightness,
+		})
+	}
+
+	// Phases and events
+	for _, ph := range rec.Phases() {
+		cs.Phases = append(cs.Phases, chartPhase{
+			Label: ph.Label,
+			Start: ph.Start.Sub(rec.Start).Seconds(),
+			End:   ph.End.Sub(rec.Start).Seconds(),
+		})
+	}
+
+	for _, m := range rec.Markers {
+		if m.Type == powerhouse.MarkerTypeEvent {
+			t := m.Time.Sub(rec.Start).Seconds()
+			cs.Markers = append(cs.Markers, chartPhase{Label: m.Label, Start: t, End: t})
+		}
+	}
+
+	// Screenshots, skipping those that can't be read
+	for i := range rec.Screenshots {
+		img, err := thumbnail(rec, &rec.Screenshots[i])
+		if err != nil {
+			continue
+		}
+
+		cs.Screenshots = append(cs.Screenshots, chartScreenshot{
+			Offset: rec.Screenshots[i].Time.Sub(rec.Start).Seconds(),
+			Image:  img,
+		})
+	}
+
+	return cs
+}
+
+// thumbnail returns a scaled-down screenshot as JPEG data URI.
+func thumbnail(rec *powerhouse.Recording, s *powerhouse.Screenshot) (string, error) {
+	// Decode
+	data, err := rec.ScreenshotData(s)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode screenshot: %w", err)
+	}
+
+	// Scale
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, max(1, b.Dy()*thumbnailWidth/max(1, b.Dx()))))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+
+	// Encode
+	var buf bytes.Buffer
+
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Warnings returns conditions that make a recording unreliable.
+func Warnings(rec *powerhouse.Recording) []string {
+	var w []string
+
+	// Crashes invalidate the recording
+	for _, cr := range rec.Crashes {
+		w = append(w, fmt.Sprintf("%s crashed at %s (%s), the recording is invalid from then on", cr.Process,
+			formatElapsed(cr.Time.Sub(rec.Start)), cr.File))
+	}
+
+	// Device state changes, e.g. an app update, mix up two different runs
+	if (rec.Metadata.StartState != nil) && (rec.Metadata.EndState != nil) {
+		for _, c := range rec.Metadata.EndState.Diff(rec.Metadata.StartState) {
+			w = append(w, fmt.Sprintf("%s changed during the recording, from %q to %q", c.Name, c.From, c.To))
+		}
+	}
+
+	// Battery samples
+	var samples []*powerhouse.Metrics
+
+	for _, m := range rec.Samples {
+		if m.Battery != nil {
+			samples = append(samples, m)
+		}
+	}
+
+	if len(samples) == 0 {
+		return append(w, "recording contains no battery samples")
+	}
+
+	// Duration
+	if d := rec.Summary().Duration; d < minDuration.Seconds() {
+		w = append(w, fmt.Sprintf("recording is shorter than %s (%.0f s)", minDuration, d))
+	}
+
+	// Power source, temperature and brightness
+	var connected, charging, hot int
+	var brightnessChanged bool
+
+	for i, m := range samples {
+		if m.Battery.IsConnected {
+			connected++
+		}
+
+		if m.Battery.IsCharging {
+			charging++
+		}
+
+		if m.Battery.Temperature > temperatureHigh {
+			hot++
+		}
+
+		if (i > 0) && (m.Backlight != nil) && (samples[i-1].Backlight != nil) &&
+			(m.Backlight.BrightnessValue != samples[i-1].Backlight.BrightnessValue) {
+			brightnessChanged = true
+		}
+	}
+
+	if connected > 0 {
+		w = append(w, fmt.Sprintf("charger connected during %d of %d samples", connected, len(samples)))
+	}
+
+	if charging > 0 {
+		w = append(w, fmt.Sprintf("battery charging during %d of %d samples", charging, len(samples)))
+	}
+
+	if hot > 0 {
+		w = append(w, fmt.Sprintf("battery temperature above %.0f °C during %d of %d samples", temperatureHigh, hot,
+			len(samples)))
+	}
+
+	if brightnessChanged {
+		w = append(w, "display brightness changed during the recording (is Auto-Brightness disabled?)")
+	}
+
+	// Gaps between samples
+	if len(samples) > 2 {
+		intervals := make([]float64, 0, len(samples)-1)
+
+		for i := 1; i < len(samples); i++ {
+			intervals = append(intervals, samples[i].Battery.Time.Sub(samples[i-1].Battery.Time).Seconds())
+		}
+
+		sorted := append([]float64(nil), intervals...)
+		sort.Float64s(sorted)
+		median := sorted[len(sorted)/2]
+
+		var gaps int
+		var longest float64
+
+		for _, iv := range intervals {
+			if iv > gapFactor*median {
+				gaps++
+				longest = math.Max(longest, iv)
+			}
+		}
+
+		if gaps > 0 {
+			w = append(w, fmt.Sprintf("%d gaps between samples (longest %.0f s, usually %.0f s)", gaps, longest,
+				median))
+		}
+	}
+
+	return w
+}
+
+// spikes returns the highest power spikes of a recording with device log lines, in chronological order, along with
+// the log lines around them. A spike is a local maximum of the power well above its median.
+func spikes(rec *powerhouse.Recording) []spikeRow {
+	// Battery samples and log lines
+	var samples []*powerhouse.BatteryMetrics
+	var lines []*powerhouse.LogLine
+
+	for _, m := range rec.Samples {
+		if m.Battery != nil {
+			samples = append(samples, m.Battery)
+		}
+
+		if m.Log != nil {
+			lines = append(lines, m.Log)
+		}
+	}
+
+	if (len(lines) == 0) || (len(samples) < 3) {
+		return nil
+	}
+
+	// Median power
+	powers := make([]float64, len(samples))
+	for i, b := range samples {
+		powers[i] = b.Power()
+	}
+
+	sorted := append([]float64(nil), powers...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	// Local maxima well above the median, highest first
+	var peaks []int
+
+	for i, p := range powers {
+		if (p > spikeFactor*median) && ((i == 0) || (p >= powers[i-1])) && ((i == len(powers)-1) || (p > powers[i+1])) {
+			peaks = append(peaks, i)
+		}
+	}
+
+	sort.Slice(peaks, func(a, b int) bool { return powers[peaks[a]] > powers[peaks[b]] })
+	peaks = peaks[:min(len(peaks), maxSpikes)]
+	sort.Ints(peaks)
+
+	// Log lines around each spike
+	var rows []spikeRow
+
+	for _, i := range peaks {
+		t := samples[i].Time
+		row := spikeRow{Offset: t.Sub(rec.Start), Power: powers[i]}
+
+		// Lines within the window, closest to the spike first
+		var window []*powerhouse.LogLine
+
+		for _, l := range lines {
+			if !l.Time.Before(t.Add(-spikeLinesBefore)) && !l.Time.After(t.Add(spikeLinesAfter)) {
+				window = append(window, l)
+			}
+		}
+
+		sort.SliceStable(window, func(a, b int) bool {
+			return window[a].Time.Sub(t).Abs() < window[b].Time.Sub(t).Abs()
+		})
+
+		window = window[:min(len(window), maxLinesPerSpike)]
+
+		// Keep them in chronological order
+		sort.SliceStable(window, func(a, b int) bool { return window[a].Time.Before(window[b].Time) })
+
+		for _, l := range window {
+			row.Lines = append(row.Lines, logRow{LogLine: *l, Delta: fmt.Sprintf("%+.0f s", l.Time.Sub(t).Seconds())})
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// delta formats the relative difference of a value to the baseline, and returns true if the value is higher.
+func delta(v float64, base float64) (string, bool) {
+	if base == 0 {
+		return "n/a", false
+	}
+
+	d := (v - base) / base * 100.0
+
+	return fmt.Sprintf("%+.1f %%", d), d > 0
+}
+
+// formatElapsed formats a duration as "hh:mm:ss".
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config configures TLS and authentication of a network listener.
+type Config struct {
+	// CertFile and KeyFile are PEM encoded server certificate and key. TLS is enabled if both are set.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is a PEM encoded CA bundle. If set, clients may authenticate with a certificate signed by one of
+	// these CAs (mutual TLS).
+	ClientCAFile string
+
+	// Tokens are accepted as bearer tokens in the "Authorization" header.
+	Tokens []string
+
+	// Insecure allows listening on a non-loopback address without TLS, which sends bearer tokens in cleartext.
+	Insecure bool
+
+	// Public returns true for requests that are served without authentication, e.g. for static assets that don't
+	// expose any data. If nil, all requests must be authenticated.
+	Public func(r *http.Request) bool
+}
+
+// Serve serves the handler on the given address until the context is canceled. Requests must be authenticated via
+// bearer token or client certificate, unless they originate from a loopback address or are public. Listening on a
+// non-loopback address without any means of authentication, or without TLS (unless insecure), is refused.
+//
+// Note that requests forwarded by a reverse proxy on the same host originate from a loopback address, and thus skip
+// authentication. Such a proxy has to authenticate requests itself.
+func Serve(ctx context.Context, addr string, handler http.Handler, cfg Config) error {
+	// Check authentication
+	if !isLoopbackAddr(addr) && (len(cfg.Tokens) == 0) && (cfg.ClientCAFile == "") {
+		return fmt.Errorf("refusing to listen on non-loopback address %s without tokens or client CA", addr)
+	}
+
+	// Create TLS config
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("create TLS config: %w", err)
+	}
+
+	// Check encryption
+	if !isLoopbackAddr(addr) && (tlsConfig == nil) && !cfg.Insecure {
+		return fmt.Errorf("refusing to listen on non-loopback address %s without TLS certificate and key", addr)
+	}
+
+	// Create server
+	hs := &http.Server{
+		Addr:              addr,
+		Handler:           cfg.authenticate(handler),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Shut down once context is canceled
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_ = hs.Shutdown(shutdownCtx)
+	}()
+
+	// Serve
+	if tlsConfig != nil {
+		err = hs.ListenAndServeTLS("", "")
+	} else {
+		err = hs.ListenAndServe()
+	}
+
+	if (err != nil) && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	return nil
+}
+
+// tlsConfig creates the TLS config, or returns nil if TLS is disabled.
+func (cfg *Config) tlsConfig() (*tls.Config, error) {
+	if (cfg.CertFile == "") && (cfg.KeyFile == "") {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("client CA requires TLS certificate and key")
+		}
+
+		return nil, nil //nolint
+	}
+
+	// Load server certificate
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	// Load client CAs
+	if cfg.ClientCAFile != "" {
+		data, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("parse client CA: no certificates found")
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// authenticate wraps the handler to reject unauthenticated requests from non-loopback addresses, unless they are
+// public.
+func (cfg *Config) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Public
+		if (cfg.Public != nil) && cfg.Public(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Verified client certificate
+		if (r.TLS != nil) && (len(r.TLS.VerifiedChains) > 0) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Bearer token, either in the header or as query parameter (browsers can't set headers for WebSockets)
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token, ok = r.URL.Query().Get("access_token"), r.URL.Query().Has("access_token")
+		}
+
+		if ok {
+			for _, t := range cfg.Tokens {
+				if (t != "") && (subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		// Loopback
+		if isLoopbackAddr(r.RemoteAddr) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// isLoopbackAddr returns true if the host part of the given address is a loopback address. An empty host (listening
+// on all interfaces) is not a loopback address.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	return (ip != nil) && ip.IsLoopback()
+}
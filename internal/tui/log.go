@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"context"
+	"log/slog"
+)
+
+// bufferedRecord is a log record, together with the handler to write it with later on.
+type bufferedRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+// logHandler buffers log records while the view is shown, and shows the most recent ones in the view.
+type logHandler struct {
+	t    *TUI
+	next slog.Handler
+}
+
+// Enabled ...
+func (h *logHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle ...
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.t.logMu.Lock()
+	defer h.t.logMu.Unlock()
+
+	// View is closed already
+	if h.t.closed {
+		return h.next.Handle(ctx, r)
+	}
+
+	h.t.logs = append(h.t.logs, bufferedRecord{handler: h.next, record: r.Clone()})
+
+	return nil
+}
+
+// WithAttrs ...
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{t: h.t, next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup ...
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{t: h.t, next: h.next.WithGroup(name)}
+}
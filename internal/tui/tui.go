@@ -0,0 +1,370 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+
+	"github.com/crissyfield/powerhouse/pkg/powerhouse"
+)
+
+const (
+	sparklineLength = 60
+	logLength       = 3
+	temperatureHigh = 35.0
+)
+
+// sparkTicks are the characters used to draw sparklines, from low to high.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// TUI draws a full-screen terminal view of a live measurement of one or more devices.
+//
+// Pausing freezes the view, while the measurement itself goes on. Once resumed, the view catches up with everything
+// measured in the meantime.
+type TUI struct {
+	// Terminal. Reading keys stops once stop is closed, and done is closed once it stopped
+	in    *os.File
+	out   *os.File
+	state *term.State
+	keys  chan byte
+	stop  chan struct{}
+	done  chan struct{}
+
+	// Logging, which is buffered while the view is shown
+	logger *slog.Logger
+	logMu  sync.Mutex
+	logs   []bufferedRecord
+	closed bool
+
+	// Measurement state
+	start   time.Time
+	paused  bool
+	screen  string
+	panels  []*panel
+	markers []powerhouse.Marker
+}
+
+// panel is the part of the view showing a single device.
+type panel struct {
+	dev     *powerhouse.Device
+	last    *powerhouse.Metrics
+	power   []float64
+	samples int
+	energy  float64
+}
+
+// New switches the terminal to raw mode and the alternate screen, and returns a TUI for the given devices. Log
+// records are buffered until the TUI is closed.
+func New(devs []*powerhouse.Device) (*TUI, error) {
+	// Raw mode
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("switch terminal to raw mode: %w", err)
+	}
+
+	t := &TUI{
+		in:     os.Stdin,
+		out:    os.Stdout,
+		state:  state,
+		keys:   make(chan byte),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		logger: slog.Default(),
+		start:  time.Now(),
+	}
+
+	for _, dev := range devs {
+		t.panels = append(t.panels, &panel{dev: dev})
+	}
+
+	// Buffer log records, which would otherwise be written across the view
+	slog.SetDefault(slog.New(&logHandler{t: t, next: t.logger.Handler()}))
+
+	// Alternate screen, hidden cursor
+	_, _ = fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+
+	// Read keys
+	go t.readKeys()
+
+	t.Draw()
+
+	return t, nil
+}
+
+// Close stops reading keys, restores the terminal and logging, and writes all buffered log records.
+func (t *TUI) Close() {
+	close(t.stop)
+	<-t.done
+
+	_, _ = fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	_ = term.Restore(int(t.in.Fd()), t.state)
+
+	slog.SetDefault(t.logger)
+
+	t.logMu.Lock()
+	defer t.logMu.Unlock()
+
+	for _, br := range t.logs {
+		_ = br.handler.Handle(context.Background(), br.record)
+	}
+
+	t.logs = nil
+	t.closed = true
+}
+
+// Keys returns a channel that receives all pressed keys.
+func (t *TUI) Keys() <-chan byte {
+	return t.keys
+}
+
+// readKeys sends pressed keys until the TUI is closed. Stdin is polled instead of blocking in a read, so no input is
+// consumed once the TUI is closed.
+func (t *TUI) readKeys() {
+	defer close(t.done)
+
+	fds := []unix.PollFd{{Fd: int32(t.in.Fd()), Events: unix.POLLIN}}
+	buf := make([]byte, 1)
+
+	for {
+		select {
+		case <-t.stop:
+			return
+
+		default:
+		}
+
+		// Wait for input, but check for stop regularly
+		n, err := unix.Poll(fds, 100)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		if n == 0 {
+			continue
+		}
+
+		// Read key
+		if _, err := t.in.Read(buf); err != nil {
+			return
+		}
+
+		select {
+		case t.keys <- buf[0]:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// Update adds the given metrics of a device, and redraws.
+func (t *TUI) Update(dev *powerhouse.Device, m *powerhouse.Metrics) {
+	if m.Marker != nil {
+		t.markers = append(t.markers, *m.Marker)
+	}
+
+	for _, p := range t.panels {
+		if (p.dev == dev) && (m.Battery != nil) {
+			p.update(t.start, m)
+		}
+	}
+
+	t.Draw()
+}
+
+// update adds a battery sample. The power of a sample is assumed to be constant until the next one, as in summaries.
+func (p *panel) update(start time.Time, m *powerhouse.Metrics) {
+	if p.last != nil {
+		from := p.last.Battery.Time
+		if from.Before(start) {
+			from = start
+		}
+
+		if m.Battery.Time.After(from) {
+			p.energy += p.last.Battery.Power() * m.Battery.Time.Sub(from).Hours()
+		}
+	}
+
+	p.last = m
+	p.samples++
+	p.power = append(p.power, m.Battery.Power())
+
+	if len(p.power) > sparklineLength {
+		p.power = p.power[len(p.power)-sparklineLength:]
+	}
+}
+
+// Mark adds an event marker, redraws, and returns the marker.
+func (t *TUI) Mark() powerhouse.Marker {
+	m := powerhouse.Marker{
+		Time:  time.Now(),
+		Type:  powerhouse.MarkerTypeEvent,
+		Label: fmt.Sprintf("mark %d", len(t.markers)+1),
+	}
+
+	t.markers = append(t.markers, m)
+	t.Draw()
+
+	return m
+}
+
+// TogglePause freezes or resumes the view, and redraws.
+func (t *TUI) TogglePause() {
+	t.paused = !t.paused
+	t.screen = t.render()
+	t.Draw()
+}
+
+// Draw redraws the whole screen, unless the view is frozen.
+func (t *TUI) Draw() {
+	if !t.paused {
+		t.screen = t.render()
+	}
+
+	// Clear screen and draw (raw mode requires carriage returns)
+	_, _ = fmt.Fprint(t.out, "\x1b[H\x1b[2J"+strings.ReplaceAll(t.screen, "\n", "\r\n"))
+}
+
+// render renders the whole screen.
+func (t *TUI) render() string {
+	var b strings.Builder
+
+	// Header
+	fmt.Fprintf(&b, "\x1b[1mpowerhouse\x1b[0m    elapsed %s", formatElapsed(time.Since(t.start)))
+
+	if t.paused {
+		b.WriteString("    \x1b[7m PAUSED \x1b[0m")
+	}
+
+	b.WriteString("\n")
+
+	// Devices
+	for _, p := range t.panels {
+		b.WriteString("\n")
+		p.render(&b, t.start)
+	}
+
+	// Markers
+	if n := len(t.markers); n > 0 {
+		last := t.markers[n-1]
+		fmt.Fprintf(&b, "\nMarkers: %d (last: %s at %s)\n", n, last.Label, formatElapsed(last.Time.Sub(t.start)))
+	}
+
+	// Log
+	t.logMu.Lock()
+
+	if len(t.logs) > 0 {
+		b.WriteString("\n")
+
+		for _, br := range t.logs[max(len(t.logs)-logLength, 0):] {
+			fmt.Fprintf(&b, "\x1b[2m%s %-5s %s\x1b[0m\n", br.record.Time.Format(time.TimeOnly), br.record.Level,
+				br.record.Message)
+		}
+	}
+
+	t.logMu.Unlock()
+
+	// Help
+	b.WriteString("\n\x1b[2m[m] marker   [p] pause view   [q] stop\x1b[0m\n")
+
+	return b.String()
+}
+
+// render renders the panel of a device.
+func (p *panel) render(b *strings.Builder, start time.Time) {
+	fmt.Fprintf(b, "\x1b[1m%s\x1b[0m (%s, iOS %s)\n", p.dev.Name, p.dev.Type, p.dev.OSVersion)
+
+	if p.last == nil {
+		b.WriteString("Waiting for first sample...\n")
+		return
+	}
+
+	bat := p.last.Battery
+
+	fmt.Fprintf(b, "Power        %7.3f W    %s\n", bat.Power(), sparkline(p.power))
+	fmt.Fprintf(b, "Current      %7.3f A    Voltage      %6.3f V\n", -bat.InstantAmperage, bat.Voltage)
+	fmt.Fprintf(b, "Capacity     %7d %%    Temperature  %6.1f °C\n", bat.CurrentCapacity, bat.Temperature)
+
+	if p.last.Backlight != nil {
+		fmt.Fprintf(b, "Brightness   %7d      (raw %d)\n", p.last.Backlight.BrightnessValue,
+			p.last.Backlight.RawBrightnessValue)
+	}
+
+	var avg float64
+	if d := bat.Time.Sub(start).Hours(); d > 0 {
+		avg = p.energy / d
+	}
+
+	fmt.Fprintf(b, "Energy       %7.2f mWh  (avg %.3f W, %d samples)\n", p.energy*1000.0, avg, p.samples)
+
+	// Warnings
+	for _, w := range warnings(bat) {
+		fmt.Fprintf(b, "\x1b[33mWARNING: %s\x1b[0m\n", w)
+	}
+}
+
+// warnings returns conditions that make a measurement unreliable.
+func warnings(bat *powerhouse.BatteryMetrics) []string {
+	var w []string
+
+	if bat.IsConnected {
+		w = append(w, "charger connected")
+	}
+
+	if bat.IsCharging {
+		w = append(w, "battery is charging")
+	}
+
+	if bat.Temperature > temperatureHigh {
+		w = append(w, fmt.Sprintf("battery temperature above %.0f °C", temperatureHigh))
+	}
+
+	return w
+}
+
+// sparkline draws the values as a sparkline.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	// Value range
+	lo, hi := math.Inf(1), math.Inf(-1)
+
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	// Draw
+	s := make([]rune, len(values))
+
+	for i, v := range values {
+		idx := 0
+		if hi > lo {
+			idx = int((v - lo) / (hi - lo) * float64(len(sparkTicks)-1))
+		}
+
+		s[i] = sparkTicks[idx]
+	}
+
+	return string(s)
+}
+
+// formatElapsed formats a duration as "hh:mm:ss".
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -32,9 +32,26 @@ func init() {
 	CmdRoot.PersistentFlags().StringP("log-level", "l", "info", "verbosity of logging output")
 	CmdRoot.PersistentFlags().BoolP("log-as-json", "j", false, "change logging format to JSON")
 
+	// Connection
+	CmdRoot.PersistentFlags().StringP("usbmux-address", "a", "", "usbmuxd address as unix:/path or tcp:host:port")
+
+	CmdRoot.PersistentFlags().StringSliceP("host", "H", nil, "connect directly to the network device at this address, without usbmuxd")
+	CmdRoot.PersistentFlags().BoolP("browse", "b", false, "discover network devices via mDNS and connect directly, without usbmuxd")
+
+	// Pairing
+	CmdRoot.PersistentFlags().StringP("pair-records", "p", "", "directory or file to read pair records from (default usbmuxd)")
+
 	// Subcommands
+	CmdRoot.AddCommand(cmd.CmdCheck)
+	CmdRoot.AddCommand(cmd.CmdDaemon)
 	CmdRoot.AddCommand(cmd.CmdList)
+	CmdRoot.AddCommand(cmd.CmdLockdown)
 	CmdRoot.AddCommand(cmd.CmdMeasure)
+	CmdRoot.AddCommand(cmd.CmdPair)
+	CmdRoot.AddCommand(cmd.CmdPlot)
+	CmdRoot.AddCommand(cmd.CmdReport)
+	CmdRoot.AddCommand(cmd.CmdUnpair)
+	CmdRoot.AddCommand(cmd.CmdValidate)
 }
 
 // setup will set up configuration management and logging.
@@ -0,0 +1,167 @@
+package powerhouse
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// App describes an installed app.
+type App struct {
+	BundleID string // Bundle identifier
+	Name     string // Display name
+	Version  string // Marketing version (CFBundleShortVersionString)
+	Build    string // Build number (CFBundleVersion)
+}
+
+// FullVersion returns the marketing version together with the build number, e.g. "1.2 (345)".
+func (app *App) FullVersion() string {
+	return fmt.Sprintf("%s (%s)", app.Version, app.Build)
+}
+
+// LaunchedApp describes an app that was launched for a recording.
+type LaunchedApp struct {
+	App
+
+	// PID of the launched process.
+	PID int
+
+	// Killed is true if the app was killed at the end of the recording.
+	Killed bool
+}
+
+// App returns information on the installed app with the given bundle ID.
+func (dev *Device) App(bundleID string) (*App, error) {
+	var app *App
+
+	err := dev.withLockdownSession(func(_ *idevice.LockdownClient, lds *idevice.LockdownSession) error {
+		apps, err := lookupApps(lds, []string{bundleID})
+		if err != nil {
+			return err
+		}
+
+		if len(apps) == 0 {
+			return fmt.Errorf("app %s is not installed", bundleID)
+		}
+
+		app = &apps[0]
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// LaunchApp launches the app with the given bundle ID, killing a running instance first, and returns the launched
+// app. This requires the developer disk image to be mounted.
+func (dev *Device) LaunchApp(bundleID string) (*LaunchedApp, error) {
+	// Read app info, which fails early for apps that aren't installed
+	app, err := dev.App(bundleID)
+	if err != nil {
+		return nil, fmt.Errorf("get app info: %w", err)
+	}
+
+	// Launch
+	var pid int
+
+	err = dev.withInstruments(func(ic *idevice.InstrumentsClient) error {
+		pid, err = ic.LaunchApp(bundleID, true)
+		if err != nil {
+			return fmt.Errorf("launch app: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LaunchedApp{App: *app, PID: pid}, nil
+}
+
+// KillProcess kills the process with the given PID. This requires the developer disk image to be mounted.
+func (dev *Device) KillProcess(pid int) error {
+	return dev.withInstruments(func(ic *idevice.InstrumentsClient) error {
+		err := ic.KillProcess(pid)
+		if err != nil {
+			return fmt.Errorf("kill process: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// withInstruments calls fn with an instruments client.
+func (dev *Device) withInstruments(fn func(ic *idevice.InstrumentsClient) error) error {
+	return dev.withLockdownSession(func(_ *idevice.LockdownClient, lds *idevice.LockdownSession) error {
+		// Start instruments
+		ic, err := lds.StartInstrumentsService()
+		if err != nil {
+			return fmt.Errorf("start instruments service: %w", err)
+		}
+
+		defer ic.Close()
+
+		return fn(ic)
+	})
+}
+
+// lookupApps returns information on the installed apps with the given bundle IDs, in the same order. Apps that aren't
+// installed are skipped.
+func lookupApps(lds *idevice.LockdownSession, bundleIDs []string) ([]App, error) {
+	// Start installation proxy
+	ipc, err := lds.StartInstallationProxyService()
+	if err != nil {
+		return nil, fmt.Errorf("start installation proxy service: %w", err)
+	}
+
+	defer ipc.Close()
+
+	// Look up apps
+	res, err := ipc.Lookup(
+		bundleIDs,
+		[]string{"CFBundleIdentifier", "CFBundleDisplayName", "CFBundleShortVersionString", "CFBundleVersion"},
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("look up apps: %w", err)
+	}
+
+	var apps []App
+
+	for _, bundleID := range bundleIDs {
+		info, ok := res[bundleID]
+		if !ok {
+			continue
+		}
+
+		// Parse app info
+		var ai struct {
+			CFBundleIdentifier         string `mapstructure:"CFBundleIdentifier"`
+			CFBundleDisplayName        string `mapstructure:"CFBundleDisplayName"`
+			CFBundleShortVersionString string `mapstructure:"CFBundleShortVersionString"`
+			CFBundleVersion            string `mapstructure:"CFBundleVersion"`
+		}
+
+		err = mapstructure.Decode(info, &ai)
+		if err != nil {
+			return nil, fmt.Errorf("parse app info of %s: %w", bundleID, err)
+		}
+
+		apps = append(apps, App{
+			BundleID: ai.CFBundleIdentifier,
+			Name:     ai.CFBundleDisplayName,
+			Version:  ai.CFBundleShortVersionString,
+			Build:    ai.CFBundleVersion,
+		})
+	}
+
+	return apps, nil
+}
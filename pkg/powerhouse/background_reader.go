@@ -0,0 +1,43 @@
+package powerhouse
+
+// backgroundReader reads values from a device service in a Go routine, so that they can be received in a select
+// together with other events. Reading stops at the first error, e.g. once the client of the service is closed.
+type backgroundReader[T any] struct {
+	// Values read, closed once reading stopped
+	values chan T
+
+	// Error that stopped reading
+	failed chan error
+}
+
+// readInBackground starts reading values with the given function.
+func readInBackground[T any](read func() (T, error)) *backgroundReader[T] {
+	r := &backgroundReader[T]{values: make(chan T), failed: make(chan error, 1)}
+
+	go func() {
+		defer close(r.values)
+
+		for {
+			v, err := read()
+			if err != nil {
+				r.failed <- err
+				return
+			}
+
+			r.values <- v
+		}
+	}()
+
+	return r
+}
+
+// err returns the error that stopped reading. It must only be called once the values channel is closed.
+func (r *backgroundReader[T]) err() error {
+	return <-r.failed
+}
+
+// drain discards values until reading stopped. The client of the service must be closed first, to stop reading.
+func (r *backgroundReader[T]) drain() {
+	for range r.values { //nolint
+	}
+}
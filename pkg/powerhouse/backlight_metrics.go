@@ -8,7 +8,7 @@ import (
 	"github.com/crissyfield/powerhouse/internal/idevice"
 )
 
-// BacklightMetrics ...
+// BacklightMetrics is a sample of the display brightness, as reported by the diagnostics relay.
 type BacklightMetrics struct {
 	RawBrightnessMin   uint64
 	RawBrightnessMax   uint64
@@ -9,7 +9,7 @@ import (
 	"github.com/crissyfield/powerhouse/internal/idevice"
 )
 
-// BatteryMetricsAdapterDetails ...
+// BatteryMetricsAdapterDetails describes the power adapter the device is connected to.
 type BatteryMetricsAdapterDetails struct {
 	// Description could be "batt", "usb host", "baseline arcas", "pd charger", "usb charger", or "magsave acc".
 	Description string
@@ -24,7 +24,7 @@ type BatteryMetricsAdapterDetails struct {
 	Watts float64
 }
 
-// BatteryMetrics ...
+// BatteryMetrics is a sample of the battery state, as reported by the diagnostics relay.
 type BatteryMetrics struct {
 	// Time this metric was generated.
 	Time time.Time
@@ -149,3 +149,9 @@ func batteryMetricsFromDiagnosticRelayClient(drc *idevice.DiagnosticRelayClient)
 		},
 	}, nil
 }
+
+// Power returns the power drawn from the battery (in W), which is positive when discharging and negative when
+// charging.
+func (m *BatteryMetrics) Power() float64 {
+	return -m.Voltage * m.InstantAmperage
+}
@@ -0,0 +1,246 @@
+package powerhouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// crashReportNameRegexp parses the name of a crash report file, e.g. "MyApp-2026-10-18-123456.ips".
+var crashReportNameRegexp = regexp.MustCompile(`^(.+?)-\d{4}-\d{2}-\d{2}-\d{6}`)
+
+// crashBugTypes are the bug types of crash reports that describe crashes, as opposed to e.g. hangs or jetsam events.
+var crashBugTypes = []string{"109", "309"}
+
+// CrashReport is the report of a process that crashed during a recording.
+type CrashReport struct {
+	// Time of the crash.
+	Time time.Time
+
+	// Process that crashed.
+	Process string
+
+	// File name of the report on the device.
+	File string
+
+	// Content of the report.
+	Content string
+}
+
+// CrashFilter selects crash reports by process. An empty filter selects all crash reports.
+type CrashFilter struct {
+	Processes []string
+}
+
+// Match returns true if the filter selects the crash report.
+func (f *CrashFilter) Match(cr *CrashReport) bool {
+	return f.matchProcess(cr.Process)
+}
+
+// matchProcess returns true if the filter selects crash reports of the given process.
+func (f *CrashFilter) matchProcess(process string) bool {
+	return (len(f.Processes) == 0) || slices.Contains(f.Processes, process)
+}
+
+// reportCrashReports lists the crash reports on the device, and returns a function that checks for new crash reports
+// every interval until the context is canceled, and once more at the end. It sends an event marker and the report
+// for every new crash selected by the filter to the given channel. Failed checks are logged, and retried with the next
+// one.
+func (dev *Device) reportCrashReports(
+	ctx context.Context,
+	interval time.Duration,
+	filter *CrashFilter,
+	metrics chan<- *Metrics,
+) (func(), error) {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	cleanUp := func() {
+		lds.Close()
+		ldc.Close()
+	}
+
+	// Remember existing crash reports
+	known := make(map[string]bool)
+
+	files, err := listCrashReports(lds)
+	if err != nil {
+		cleanUp()
+		return nil, fmt.Errorf("list crash reports: %w", err)
+	}
+
+	for _, f := range files {
+		known[f.Path] = true
+	}
+
+	// Check for new crash reports
+	check := func() error {
+		// List crash reports
+		crc, err := startCrashReportCopy(lds)
+		if err != nil {
+			return err
+		}
+
+		defer crc.Close()
+
+		files, err := crc.List()
+		if err != nil {
+			return fmt.Errorf("list crash reports: %w", err)
+		}
+
+		// Read new crash reports
+		for _, f := range files {
+			if known[f.Path] {
+				continue
+			}
+
+			// Only read reports of selected processes, judged by the file name
+			if !filter.matchProcess(crashReportProcess(path.Base(f.Path))) {
+				known[f.Path] = true
+				continue
+			}
+
+			data, err := crc.ReadFile(f.Path)
+			if err != nil {
+				return fmt.Errorf("read crash report: %w", err)
+			}
+
+			known[f.Path] = true
+
+			cr, ok := parseCrashReport(f, data)
+			if !ok || !filter.Match(cr) {
+				continue
+			}
+
+			// Send out
+			metrics <- &Metrics{Marker: &Marker{Time: cr.Time, Type: MarkerTypeEvent, Label: "crash " + cr.Process}}
+			metrics <- &Metrics{Crash: cr}
+		}
+
+		return nil
+	}
+
+	// Return function that checks until canceled
+	run := func() {
+		defer cleanUp()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// Check a last time, as the app might have crashed at the very end
+				if err := check(); err != nil {
+					slog.Warn("Unable to check crash reports", slog.String("udid", dev.UDID), slog.Any("error", err))
+				}
+
+				return
+
+			case <-ticker.C:
+				if err := check(); err != nil {
+					slog.Warn("Unable to check crash reports", slog.String("udid", dev.UDID), slog.Any("error", err))
+				}
+			}
+		}
+	}
+
+	return run, nil
+}
+
+// listCrashReports moves new crash reports, and lists all of them.
+func listCrashReports(lds *idevice.LockdownSession) ([]idevice.CrashReportFile, error) {
+	crc, err := startCrashReportCopy(lds)
+	if err != nil {
+		return nil, err
+	}
+
+	defer crc.Close()
+
+	return crc.List()
+}
+
+// startCrashReportCopy moves new crash reports, and starts the crash report copy service.
+func startCrashReportCopy(lds *idevice.LockdownSession) (*idevice.CrashReportClient, error) {
+	if err := lds.MoveCrashReports(); err != nil {
+		return nil, fmt.Errorf("move crash reports: %w", err)
+	}
+
+	crc, err := lds.StartCrashReportCopyService()
+	if err != nil {
+		return nil, fmt.Errorf("start crash report copy service: %w", err)
+	}
+
+	return crc, nil
+}
+
+// parseCrashReport parses a crash report file. It returns false if the file doesn't describe a crash.
+func parseCrashReport(f idevice.CrashReportFile, data []byte) (*CrashReport, bool) {
+	name := path.Base(f.Path)
+	ext := path.Ext(name)
+
+	cr := &CrashReport{Time: f.ModTime, Process: crashReportProcess(name), File: name, Content: string(data)}
+
+	// Reports in the JSON format start with a header line
+	line, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+
+	var header struct {
+		BugType   string `json:"bug_type"`
+		Name      string `json:"name"`
+		AppName   string `json:"app_name"`
+		Timestamp string `json:"timestamp"`
+	}
+
+	if err := json.Unmarshal(line, &header); err != nil {
+		// Legacy text reports
+		return cr, ext == ".crash"
+	}
+
+	if !slices.Contains(crashBugTypes, header.BugType) {
+		return nil, false
+	}
+
+	switch {
+	case header.Name != "":
+		cr.Process = header.Name
+
+	case header.AppName != "":
+		cr.Process = header.AppName
+	}
+
+	if t, err := time.Parse("2006-01-02 15:04:05 -0700", header.Timestamp); err == nil {
+		cr.Time = t
+	}
+
+	return cr, true
+}
+
+// crashReportProcess returns the process of a crash report by its file name. The report itself might name the process
+// differently.
+func crashReportProcess(name string) string {
+	if match := crashReportNameRegexp.FindStringSubmatch(name); match != nil {
+		return match[1]
+	}
+
+	return strings.TrimSuffix(name, path.Ext(name))
+}
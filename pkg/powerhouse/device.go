@@ -0,0 +1,455 @@
+package powerhouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// Device wraps information of a specific iDevice.
+type Device struct {
+	ConnectionType string // Either "USB" or "Network"
+	UDID           string // Unique device ID
+	Name           string // Device name
+	Type           string // Device type
+	OSVersion      string // Version of the installed OS
+	OSBuild        string // Build number of the installed OS
+	WiFiAddress    string // MAC address of the device
+
+	idev *idevice.Device
+}
+
+// newDevice creates a new iDevice.
+func newDevice(idev *idevice.Device) (*Device, error) {
+	// Get device info
+	info, err := idev.Info()
+	if err != nil {
+		return nil, fmt.Errorf("get device info: %w", err)
+	}
+
+	// Parse device info
+	var di struct {
+		UniqueDeviceID string `mapstructure:"UniqueDeviceID"`
+		DeviceName     string `mapstructure:"DeviceName"`
+		ProductType    string `mapstructure:"ProductType"`
+		ProductVersion string `mapstructure:"ProductVersion"`
+		BuildVersion   string `mapstructure:"BuildVersion"`
+		WiFiAddress    string `mapstructure:"WiFiAddress"`
+	}
+
+	err = mapstructure.Decode(info, &di)
+	if err != nil {
+		return nil, fmt.Errorf("parse device info: %w", err)
+	}
+
+	// Return device
+	return &Device{
+		ConnectionType: idev.ConnectionType(),
+		UDID:           di.UniqueDeviceID,
+		Name:           di.DeviceName,
+		Type:           di.ProductType,
+		OSVersion:      di.ProductVersion,
+		OSBuild:        di.BuildVersion,
+		WiFiAddress:    di.WiFiAddress,
+		idev:           idev,
+	}, nil
+}
+
+// Metrics is a single item reported by a device. Err is set if reporting failed, otherwise the fields of the
+// collector that reported the item, e.g. Battery and Backlight, or Processes, CPU, Memory and Network.
+type Metrics struct {
+	Err        error             // Error that stops reporting, see Classify
+	Battery    *BatteryMetrics   // Battery sample
+	Backlight  *BacklightMetrics // Backlight sample, together with Battery
+	Processes  *ProcessesMetrics // Per-process sample (MetricsOptions.Processes)
+	CPU        *CPUMetrics       // System-wide CPU sample (MetricsOptions.CPU)
+	GPU        *GPUMetrics       // GPU sample (MetricsOptions.GPU)
+	Memory     *MemoryMetrics    // System-wide memory sample (MetricsOptions.Memory)
+	Network    *NetworkMetrics   // Network traffic sample (MetricsOptions.Network)
+	Log        *LogLine          // Device log line (MetricsOptions.Syslog)
+	Marker     *Marker           // Marker from the device log, a notification or a crash, not a sample
+	Capture    *NetworkMetrics   // Captured packets per interval (MetricsOptions.Capture)
+	Crash      *CrashReport      // Crash report (MetricsOptions.Crashes), not a sample
+	Screenshot *Screenshot       // Screenshot (MetricsOptions.Screenshots), not a sample
+}
+
+// metricsJSON is the JSON layout of metrics, with the error as class and message.
+type metricsJSON struct {
+	Err *ErrorInfo
+	*metricsFields
+}
+
+// metricsFields has the fields of metrics, without its methods.
+type metricsFields Metrics
+
+// MarshalJSON encodes the metrics, with the error as ErrorInfo.
+func (m Metrics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metricsJSON{Err: newErrorInfo(m.Err), metricsFields: (*metricsFields)(&m)})
+}
+
+// UnmarshalJSON decodes the metrics, with the error as ErrorInfo.
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	mj := metricsJSON{metricsFields: (*metricsFields)(m)}
+
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return err
+	}
+
+	m.Err = nil
+
+	if mj.Err != nil {
+		m.Err = mj.Err
+	}
+
+	return nil
+}
+
+// Time returns the time of the sample.
+func (m *Metrics) Time() time.Time {
+	switch {
+	case m.Battery != nil:
+		return m.Battery.Time
+
+	case m.Processes != nil:
+		return m.Processes.Time
+
+	case m.CPU != nil:
+		return m.CPU.Time
+
+	case m.GPU != nil:
+		return m.GPU.Time
+
+	case m.Memory != nil:
+		return m.Memory.Time
+
+	case m.Network != nil:
+		return m.Network.Time
+
+	case m.Log != nil:
+		return m.Log.Time
+
+	case m.Marker != nil:
+		return m.Marker.Time
+
+	case m.Capture != nil:
+		return m.Capture.Time
+
+	case m.Crash != nil:
+		return m.Crash.Time
+
+	case m.Screenshot != nil:
+		return m.Screenshot.Time
+
+	default:
+		return time.Time{}
+	}
+}
+
+// MetricsOptions enables optional collectors. Their metrics are reported separately from battery and backlight
+// metrics. All but the syslog, the packet capture, the crash reports and the notifications read the instruments
+// service, which requires the developer disk image to be mounted (as do screenshots).
+type MetricsOptions struct {
+	// Processes enables per-process CPU usage, wakeups and energy impact.
+	Processes bool
+
+	// CPU, GPU, Memory and Network enable the system-wide CPU load, GPU utilization, memory usage and network
+	// traffic.
+	CPU     bool
+	GPU     bool
+	Memory  bool
+	Network bool
+
+	// Syslog enables the device log lines selected by the filter, if not nil.
+	Syslog *LogFilter
+
+	// LogMarkers turn matching device log lines into markers, which are reported separately from the log lines.
+	LogMarkers []LogMarkerRule
+
+	// Capture enables packet capture, if not nil. All packets are written as pcapng file to the writer, and the
+	// traffic is reported every second (if there was any).
+	Capture io.Writer
+
+	// Crashes enables the crash reports selected by the filter, if not nil. Each crash is also reported as an event
+	// marker.
+	Crashes *CrashFilter
+
+	// Screenshots enables a screenshot every interval, if not zero. Screenshots are written as PNG files to
+	// ScreenshotDir as they are taken, which is created if needed, and only reported by file name.
+	Screenshots   time.Duration
+	ScreenshotDir string
+
+	// Notifications enables an event marker for each of the device notifications with these names, e.g. those in
+	// DefaultNotifications.
+	Notifications []string
+}
+
+// ReportMetrics starts reporting battery and backlight metrics, and the metrics of the enabled optional collectors,
+// on the returned channel, until the context is canceled.
+func (dev *Device) ReportMetrics(ctx context.Context, opts MetricsOptions) (<-chan *Metrics, error) {
+	if (opts.Screenshots > 0) && (opts.ScreenshotDir == "") {
+		return nil, errors.New("screenshots require a screenshot directory")
+	}
+
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown lds
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start diagnostic service
+	drc, err := lds.StartDiagnosticRelayService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start diagnostic service: %w", err)
+	}
+
+	// Read initial battery metrics
+	initBattery, err := batteryMetricsFromDiagnosticRelayClient(drc)
+	if err != nil {
+		drc.Close()
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("create initial battery metrics: %w", err)
+	}
+
+	// Read initial backlight metrics
+	initBacklight, err := backlightMetricsFromDiagnosticRelayClient(drc)
+	if err != nil {
+		drc.Close()
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("create initial backlight metrics: %w", err)
+	}
+
+	// Optional collectors stop with the context, or once one of them fails to start
+	metrics := make(chan *Metrics)
+	ctx, cancel := context.WithCancel(ctx)
+
+	var collectors []func()
+
+	fail := func(err error) (<-chan *Metrics, error) {
+		cancel()
+
+		go func() {
+			for range metrics { //nolint
+			}
+		}()
+
+		for _, run := range collectors {
+			run()
+		}
+
+		close(metrics)
+		drc.Close()
+		lds.Close()
+		ldc.Close()
+
+		return nil, err
+	}
+
+	// Optionally, start instruments metrics
+	if opts.usesInstruments() {
+		run, err := dev.reportInstrumentsMetrics(ctx, 5*time.Second, opts, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start instruments metrics: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Optionally, start log lines
+	if (opts.Syslog != nil) || (len(opts.LogMarkers) > 0) {
+		run, err := dev.reportLogLines(ctx, opts.Syslog, opts.LogMarkers, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start log lines: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Optionally, start packet capture
+	if opts.Capture != nil {
+		run, err := dev.reportPacketCapture(ctx, time.Second, opts.Capture, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start packet capture: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Optionally, start crash reports
+	if opts.Crashes != nil {
+		run, err := dev.reportCrashReports(ctx, 10*time.Second, opts.Crashes, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start crash reports: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Optionally, start screenshots
+	if opts.Screenshots > 0 {
+		run, err := dev.reportScreenshots(ctx, opts.Screenshots, opts.ScreenshotDir, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start screenshots: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Optionally, start notifications
+	if len(opts.Notifications) > 0 {
+		run, err := dev.reportNotifications(ctx, opts.Notifications, metrics)
+		if err != nil {
+			return fail(fmt.Errorf("start notifications: %w", err))
+		}
+
+		collectors = append(collectors, run)
+	}
+
+	// Spawn Go routines
+	var wg sync.WaitGroup
+
+	for _, run := range collectors {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			run()
+		}()
+	}
+
+	go func() {
+		defer cancel()
+
+		// Create ticker
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		// Send initial metrics
+		metrics <- &Metrics{Battery: initBattery, Backlight: initBacklight}
+
+		// Event loop
+		lastBatteryTime := initBattery.Time
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop
+				break loop
+
+			case <-ticker.C:
+				// Read battery metrics
+				battery, err := batteryMetricsFromDiagnosticRelayClient(drc)
+				if err != nil {
+					metrics <- &Metrics{Err: fmt.Errorf("read battery metrics: %w", err)}
+					continue
+				}
+
+				// Skip duplicates
+				if battery.Time.Equal(lastBatteryTime) {
+					continue
+				}
+
+				lastBatteryTime = battery.Time
+
+				// Read backlight metrics
+				backlight, err := backlightMetricsFromDiagnosticRelayClient(drc)
+				if err != nil {
+					metrics <- &Metrics{Err: fmt.Errorf("read backlight info from device: %w", err)}
+					continue
+				}
+
+				// Send out
+				metrics <- &Metrics{Battery: battery, Backlight: backlight}
+			}
+		}
+
+		// Clean up
+		drc.Close()
+		lds.Close()
+		ldc.Close()
+
+		// We're done, once all collectors are
+		wg.Wait()
+		close(metrics)
+	}()
+
+	return metrics, nil
+}
+
+// LockdownValue reads the lockdown value for the given domain and key. An empty domain selects the global domain,
+// an empty key returns all values of the domain.
+func (dev *Device) LockdownValue(domain string, key string) (any, error) {
+	var value any
+
+	err := dev.withLockdownSession(func(ldc *idevice.LockdownClient, _ *idevice.LockdownSession) error {
+		// Read value
+		v, err := ldc.GetValue(domain, key)
+		if err != nil {
+			return fmt.Errorf("get lockdown value: %w", err)
+		}
+
+		value = v
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// SetLockdownValue writes the lockdown value for the given domain and key.
+func (dev *Device) SetLockdownValue(domain string, key string, value any) error {
+	return dev.withLockdownSession(func(ldc *idevice.LockdownClient, _ *idevice.LockdownSession) error {
+		// Write value
+		err := ldc.SetValue(domain, key, value)
+		if err != nil {
+			return fmt.Errorf("set lockdown value: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// withLockdownSession calls fn with a lockdown client that has a running session, and the session. Domains other than
+// the global domain are usually only accessible within a session, services can only be started within a session.
+func (dev *Device) withLockdownSession(fn func(ldc *idevice.LockdownClient, lds *idevice.LockdownSession) error) error {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	defer ldc.Close()
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		return fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	defer lds.Close()
+
+	return fn(ldc, lds)
+}
@@ -0,0 +1,146 @@
+package powerhouse
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// DeviceState is a snapshot of the installed apps of interest and of device settings that may affect a recording.
+type DeviceState struct {
+	// Time the snapshot was taken.
+	Time time.Time
+
+	// Apps of interest that are installed, in the order they were asked for.
+	Apps []App
+
+	// Time zone, language and locale of the device.
+	TimeZone string
+	Language string
+	Locale   string
+
+	// ActivationState of the device, e.g. "Activated".
+	ActivationState string
+
+	// Storage capacity of the device, and of its data partition, as well as the available space (in bytes).
+	TotalDiskCapacity  uint64
+	TotalDataCapacity  uint64
+	TotalDataAvailable uint64
+}
+
+// StateChange is a value that differs between two device states.
+type StateChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// State takes a snapshot of the device state, including the apps with the given bundle IDs.
+func (dev *Device) State(bundleIDs []string) (*DeviceState, error) {
+	ds := &DeviceState{Time: time.Now()}
+
+	err := dev.withLockdownSession(func(ldc *idevice.LockdownClient, lds *idevice.LockdownSession) error {
+		// Optionally, look up apps
+		if len(bundleIDs) > 0 {
+			apps, err := lookupApps(lds, bundleIDs)
+			if err != nil {
+				return err
+			}
+
+			ds.Apps = apps
+		}
+
+		// Read lockdown values of the global, international and disk usage domains
+		var lv struct {
+			TimeZone           string `mapstructure:"TimeZone"`
+			ActivationState    string `mapstructure:"ActivationState"`
+			Language           string `mapstructure:"Language"`
+			Locale             string `mapstructure:"Locale"`
+			TotalDiskCapacity  uint64 `mapstructure:"TotalDiskCapacity"`
+			TotalDataCapacity  uint64 `mapstructure:"TotalDataCapacity"`
+			TotalDataAvailable uint64 `mapstructure:"TotalDataAvailable"`
+		}
+
+		for _, domain := range []string{"", "com.apple.international", "com.apple.disk_usage"} {
+			values, err := ldc.GetValue(domain, "")
+			if err != nil {
+				return fmt.Errorf("get lockdown values of domain %q: %w", domain, err)
+			}
+
+			err = mapstructure.Decode(values, &lv)
+			if err != nil {
+				return fmt.Errorf("parse lockdown values of domain %q: %w", domain, err)
+			}
+		}
+
+		ds.TimeZone = lv.TimeZone
+		ds.Language = lv.Language
+		ds.Locale = lv.Locale
+		ds.ActivationState = lv.ActivationState
+		ds.TotalDiskCapacity = lv.TotalDiskCapacity
+		ds.TotalDataCapacity = lv.TotalDataCapacity
+		ds.TotalDataAvailable = lv.TotalDataAvailable
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// Diff returns the values that changed from the other device state to this one. Apps are compared by bundle ID.
+func (ds *DeviceState) Diff(from *DeviceState) []StateChange {
+	var changes []StateChange
+
+	add := func(name string, from string, to string) {
+		if from != to {
+			changes = append(changes, StateChange{Name: name, From: from, To: to})
+		}
+	}
+
+	// Apps
+	for _, app := range ds.Apps {
+		var version string
+
+		for _, a := range from.Apps {
+			if a.BundleID == app.BundleID {
+				version = a.FullVersion()
+				break
+			}
+		}
+
+		add(app.BundleID, version, app.FullVersion())
+	}
+
+	for _, app := range from.Apps {
+		var found bool
+
+		for _, a := range ds.Apps {
+			found = found || (a.BundleID == app.BundleID)
+		}
+
+		if !found {
+			add(app.BundleID, app.FullVersion(), "")
+		}
+	}
+
+	// Settings
+	add("Time zone", from.TimeZone, ds.TimeZone)
+	add("Language", from.Language, ds.Language)
+	add("Locale", from.Locale, ds.Locale)
+	add("Activation state", from.ActivationState, ds.ActivationState)
+	add("Storage available", formatGB(from.TotalDataAvailable), formatGB(ds.TotalDataAvailable))
+
+	return changes
+}
+
+// formatGB formats bytes as whole gigabytes, which hides the constant churn of the available space.
+func formatGB(b uint64) string {
+	return fmt.Sprintf("%.0f GB", float64(b)/1e9)
+}
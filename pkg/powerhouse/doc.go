@@ -0,0 +1,44 @@
+// Package powerhouse measures the power consumption of iDevices. It is the library behind the powerhouse command, and
+// can be embedded into test harnesses instead of running the command and parsing its JSON output.
+//
+// A Powerhouse finds connected devices, via usbmuxd or directly on the network:
+//
+//	ph, err := powerhouse.New(powerhouse.Config{})
+//	if err != nil {
+//		return err
+//	}
+//
+//	dev, err := ph.Device("", true, true)
+//	if err != nil {
+//		return err
+//	}
+//
+// A Session measures a device until it is stopped, and records all samples and markers. Its Recording can be
+// summarized, or written to a file to be read by the powerhouse command:
+//
+//	s, err := dev.StartSession(powerhouse.SessionOptions{Metrics: powerhouse.MetricsOptions{CPU: true}})
+//	if err != nil {
+//		return err
+//	}
+//
+//	s.Mark(powerhouse.MarkerTypeBegin, "checkout")
+//	runCheckout()
+//	s.Mark(powerhouse.MarkerTypeEnd, "checkout")
+//
+//	s.Stop()
+//
+//	summary := s.Summary()
+//
+// SessionOptions.OnMetrics is called with every metrics as they are reported, while Session.Subscribe drops them for
+// slow subscribers. For full control, Device.ReportMetrics reports the raw metrics on a channel instead.
+//
+// Errors can be checked with errors.Is against ErrDeviceNotFound, ErrNotPaired, ErrPasswordProtected,
+// ErrServiceUnavailable, ErrConnectionLost and ErrMalformed, or classified with Classify. Problems that don't stop
+// reporting, like a failed check for crash reports, are logged as warnings with the default slog logger.
+//
+// # Versioning
+//
+// The exported API of this package follows semantic versioning of the module: it only changes incompatibly with a new
+// major version. Fields may be added to structs, so use keyed struct literals. The JSON layout of recordings and
+// metrics is part of the API, too. Packages below internal/ are not part of the API.
+package powerhouse
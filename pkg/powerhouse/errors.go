@@ -0,0 +1,110 @@
+package powerhouse
+
+import (
+	"errors"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+var (
+	// ErrDeviceNotFound is returned if a device isn't connected (anymore).
+	ErrDeviceNotFound = idevice.ErrDeviceNotFound
+
+	// ErrNotPaired is returned if the host isn't paired with the device, or the device doesn't accept the pair record.
+	ErrNotPaired = idevice.ErrNotPaired
+
+	// ErrServiceUnavailable is returned if a service of the device can't be started, e.g. because the developer disk
+	// image isn't mounted.
+	ErrServiceUnavailable = idevice.ErrServiceUnavailable
+
+	// ErrConnectionLost is returned if the connection to the device broke down, e.g. because it was disconnected.
+	ErrConnectionLost = idevice.ErrConnectionLost
+
+	// ErrMalformed is returned if data sent by the device can't be decoded.
+	ErrMalformed = idevice.ErrMalformed
+)
+
+// ErrorClass classifies errors, so callers can decide whether to retry, re-pair or give up.
+type ErrorClass string
+
+const (
+	// ErrorClassDeviceNotFound is the class of ErrDeviceNotFound.
+	ErrorClassDeviceNotFound ErrorClass = "device-not-found"
+
+	// ErrorClassNotPaired is the class of ErrNotPaired.
+	ErrorClassNotPaired ErrorClass = "not-paired"
+
+	// ErrorClassPasswordProtected is the class of ErrPasswordProtected.
+	ErrorClassPasswordProtected ErrorClass = "password-protected"
+
+	// ErrorClassServiceUnavailable is the class of ErrServiceUnavailable.
+	ErrorClassServiceUnavailable ErrorClass = "service-unavailable"
+
+	// ErrorClassConnectionLost is the class of ErrConnectionLost.
+	ErrorClassConnectionLost ErrorClass = "connection-lost"
+
+	// ErrorClassMalformed is the class of ErrMalformed.
+	ErrorClassMalformed ErrorClass = "malformed"
+
+	// ErrorClassOther is the class of all other errors.
+	ErrorClassOther ErrorClass = "other"
+)
+
+// errorClasses maps classes to their errors, most specific first.
+var errorClasses = []struct {
+	Class ErrorClass
+	Err   error
+}{
+	{Class: ErrorClassPasswordProtected, Err: ErrPasswordProtected},
+	{Class: ErrorClassNotPaired, Err: ErrNotPaired},
+	{Class: ErrorClassDeviceNotFound, Err: ErrDeviceNotFound},
+	{Class: ErrorClassServiceUnavailable, Err: ErrServiceUnavailable},
+	{Class: ErrorClassConnectionLost, Err: ErrConnectionLost},
+	{Class: ErrorClassMalformed, Err: ErrMalformed},
+}
+
+// Classify returns the class of an error, or an empty class for nil.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	for _, ec := range errorClasses {
+		if errors.Is(err, ec.Err) {
+			return ec.Class
+		}
+	}
+
+	return ErrorClassOther
+}
+
+// ErrorInfo is the JSON representation of an error.
+type ErrorInfo struct {
+	Class   ErrorClass
+	Message string
+}
+
+// newErrorInfo returns the JSON representation of an error, or nil for nil.
+func newErrorInfo(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+
+	return &ErrorInfo{Class: Classify(err), Message: err.Error()}
+}
+
+// Error returns the message of the error.
+func (ei *ErrorInfo) Error() string {
+	return ei.Message
+}
+
+// Is returns true for the error of its class, so that errors read from JSON can be classified again.
+func (ei *ErrorInfo) Is(target error) bool {
+	for _, ec := range errorClasses {
+		if ec.Class == ei.Class {
+			return target == ec.Err
+		}
+	}
+
+	return false
+}
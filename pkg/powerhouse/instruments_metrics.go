@@ -0,0 +1,176 @@
+package powerhouse
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// usesInstruments returns true if any collector requires the instruments service.
+func (opts MetricsOptions) usesInstruments() bool {
+	return opts.Processes || opts.CPU || opts.GPU || opts.Memory || opts.Network
+}
+
+// usesSysmontap returns true if any collector reads the sysmontap channel.
+func (opts MetricsOptions) usesSysmontap() bool {
+	return opts.Processes || opts.CPU || opts.Memory || opts.Network
+}
+
+// reportInstrumentsMetrics starts the instruments channels of the enabled collectors, and returns a function that
+// sends their metrics to the given channel every interval, until the context is canceled. This requires the developer
+// disk image to be mounted.
+func (dev *Device) reportInstrumentsMetrics(
+	ctx context.Context,
+	interval time.Duration,
+	opts MetricsOptions,
+	metrics chan<- *Metrics,
+) (func(), error) {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start instruments
+	ic, err := lds.StartInstrumentsService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start instruments service: %w", err)
+	}
+
+	cleanUp := func() {
+		ic.Close()
+		lds.Close()
+		ldc.Close()
+	}
+
+	// Optionally, start sysmontap
+	var samples <-chan map[string]any
+
+	if opts.usesSysmontap() {
+		samples, err = ic.StartSysmontap(idevice.SysmontapConfig{
+			Interval:          interval,
+			ProcessAttributes: processAttributes,
+			SystemAttributes:  systemAttributes,
+		})
+
+		if err != nil {
+			cleanUp()
+			return nil, fmt.Errorf("start sysmontap: %w", err)
+		}
+	}
+
+	// Optionally, start graphics
+	var graphics <-chan map[string]any
+
+	if opts.GPU {
+		graphics, err = ic.StartGraphics()
+		if err != nil {
+			cleanUp()
+			return nil, fmt.Errorf("start graphics: %w", err)
+		}
+	}
+
+	// Return function that streams until canceled
+	run := func() {
+		// Graphics samples arrive more often than sysmontap samples, and are averaged
+		var gpu gpuAccumulator
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// Counters are cumulative, remember them
+		wakeups := make(map[int][2]uint64)
+
+		var lastSystem []float64
+
+		lastProcesses, lastSystemTime := time.Now(), time.Now()
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop
+				break loop
+
+			case sample, ok := <-samples:
+				if !ok {
+					metrics <- &Metrics{Err: fmt.Errorf("read sysmontap metrics: stream ended")}
+					break loop
+				}
+
+				now := time.Now()
+				m := &Metrics{}
+
+				// Processes
+				if opts.Processes {
+					elapsed := now.Sub(lastProcesses).Seconds()
+
+					if processes, ok := processesMetricsFromSysmontap(sample, elapsed, wakeups); ok {
+						m.Processes = &ProcessesMetrics{Time: now, Interval: elapsed, Processes: processes}
+						lastProcesses = now
+					}
+				}
+
+				// CPU
+				if opts.CPU {
+					m.CPU = cpuMetricsFromSysmontap(sample, now)
+				}
+
+				// Memory and network
+				if system, ok := systemFromSysmontap(sample); ok {
+					if opts.Memory {
+						m.Memory = memoryMetricsFromSystem(system, now)
+					}
+
+					if opts.Network {
+						m.Network = networkMetricsFromSystem(system, lastSystem, now, now.Sub(lastSystemTime).Seconds())
+					}
+
+					lastSystem, lastSystemTime = system, now
+				}
+
+				// Send out
+				if (m.Processes != nil) || (m.CPU != nil) || (m.Memory != nil) || (m.Network != nil) {
+					metrics <- m
+				}
+
+			case sample, ok := <-graphics:
+				if !ok {
+					metrics <- &Metrics{Err: fmt.Errorf("read graphics metrics: stream ended")}
+					break loop
+				}
+
+				gpu.add(sample)
+
+			case <-ticker.C:
+				// Send out averaged graphics samples
+				if m := gpu.flush(time.Now()); m != nil {
+					metrics <- &Metrics{GPU: m}
+				}
+			}
+		}
+
+		// Clean up, and drain the streams until the client is closed
+		cleanUp()
+
+		for _, ch := range []<-chan map[string]any{samples, graphics} {
+			if ch != nil {
+				for range ch { //nolint
+				}
+			}
+		}
+	}
+
+	return run, nil
+}
@@ -0,0 +1,206 @@
+package powerhouse
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// logLineRegexp parses a line of the syslog relay, e.g.
+// "Oct 18 12:34:56 iPhone SpringBoard(FrontBoard)[57] <Notice>: message".
+var logLineRegexp = regexp.MustCompile(
+	`^(\w{3} +\d{1,2} \d{2}:\d{2}:\d{2}) \S+ (.+?)(?:\(([^)]*)\))?\[(\d+)\] <(\w+)>: (.*)$`,
+)
+
+// LogLine is a line of the device log.
+type LogLine struct {
+	// Time the line was logged, on the same clock as battery samples (with a resolution of 1 s).
+	Time time.Time
+
+	// Process that logged the line, and its PID.
+	Process string
+	PID     int
+
+	// Subsystem or library the line was logged from, if reported.
+	Subsystem string
+
+	// Level of the line, e.g. "Notice" or "Error".
+	Level string
+
+	// Message of the line.
+	Message string
+}
+
+// LogFilter selects device log lines by process or subsystem. An empty filter selects all lines.
+type LogFilter struct {
+	Processes  []string
+	Subsystems []string
+}
+
+// Match returns true if the filter selects the line.
+func (f *LogFilter) Match(l *LogLine) bool {
+	if (len(f.Processes) == 0) && (len(f.Subsystems) == 0) {
+		return true
+	}
+
+	return slices.Contains(f.Processes, l.Process) || slices.Contains(f.Subsystems, l.Subsystem)
+}
+
+// reportLogLines starts the syslog relay, and returns a function that sends the log lines selected by the filter (if
+// not nil), and the markers of lines matching any of the rules, to the given channel until the context is canceled.
+func (dev *Device) reportLogLines(
+	ctx context.Context,
+	filter *LogFilter,
+	rules []LogMarkerRule,
+	metrics chan<- *Metrics,
+) (func(), error) {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Read time zone of the device, as log lines carry local time
+	var clock logClock
+
+	if v, err := ldc.GetValue("", "TimeZoneOffsetFromUTC"); err == nil {
+		if secs, ok := v.(float64); ok {
+			clock = logClock{offset: time.Duration(secs * float64(time.Second)), known: true}
+		}
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start syslog relay
+	src, err := lds.StartSyslogRelayService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start syslog relay service: %w", err)
+	}
+
+	// Return function that streams until canceled
+	run := func() {
+		// Read lines in the background, until the client is closed
+		lines := readInBackground(src.ReadLine)
+
+		// Continuation lines of multi-line messages inherit the header of the previous line
+		var last LogLine
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop
+				break loop
+
+			case line, ok := <-lines.values:
+				if !ok {
+					metrics <- &Metrics{Err: fmt.Errorf("read log line: %w", lines.err())}
+					break loop
+				}
+
+				// Parse line
+				l, ok := parseLogLine(line, &clock, time.Now())
+				switch {
+				case ok:
+					last = l
+
+				case last.Time.IsZero():
+					// Skip continuation lines without a header
+					continue
+
+				default:
+					l = last
+					l.Message = line
+				}
+
+				// Send out
+				if (filter != nil) && filter.Match(&l) {
+					metrics <- &Metrics{Log: &l}
+				}
+
+				// Send out marker of the first matching rule
+				for _, r := range rules {
+					if m, ok := r.Marker(&l); ok {
+						metrics <- &Metrics{Marker: &m}
+						break
+					}
+				}
+			}
+		}
+
+		// Clean up, and drain lines until the reader stopped
+		src.Close()
+		lds.Close()
+		ldc.Close()
+
+		lines.drain()
+	}
+
+	return run, nil
+}
+
+// logClock converts the local time of log lines to the clock of battery samples.
+type logClock struct {
+	// Offset of the device's time zone from UTC
+	offset time.Duration
+	known  bool
+}
+
+// time converts the local time of a log line, which lacks the year, received at the given time. If the time zone of
+// the device is unknown, it is estimated from the first line.
+func (c *logClock) time(local time.Time, received time.Time) time.Time {
+	local = local.AddDate(received.UTC().Year()-local.Year(), 0, 0)
+
+	// Estimate offset from the time of reception, rounded to 15 minutes
+	if !c.known {
+		c.offset = time.Duration(math.Round(local.Sub(received).Minutes()/15.0)*15.0) * time.Minute
+		c.known = true
+	}
+
+	t := local.Add(-c.offset)
+
+	// Lines received around New Year belong to the previous year
+	if t.Sub(received) > 24*time.Hour {
+		t = t.AddDate(-1, 0, 0)
+	}
+
+	return t
+}
+
+// parseLogLine parses a line of the syslog relay received at the given time. It returns false if the line doesn't
+// start with a header, e.g. for continuation lines.
+func parseLogLine(line string, clock *logClock, received time.Time) (LogLine, bool) {
+	match := logLineRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return LogLine{}, false
+	}
+
+	local, err := time.Parse("Jan _2 15:04:05", match[1])
+	if err != nil {
+		return LogLine{}, false
+	}
+
+	pid, _ := strconv.Atoi(match[4])
+
+	return LogLine{
+		Time:      clock.time(local, received),
+		Process:   match[2],
+		PID:       pid,
+		Subsystem: match[3],
+		Level:     match[5],
+		Message:   match[6],
+	}, true
+}
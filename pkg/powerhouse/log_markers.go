@@ -0,0 +1,106 @@
+package powerhouse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LogMarkerRule turns device log lines that match a pattern into markers.
+type LogMarkerRule struct {
+	// Type of the markers, or empty if it is taken from the "type" group of the pattern.
+	Type MarkerType
+
+	// Pattern matched against the message of log lines.
+	Pattern *regexp.Regexp
+}
+
+// ParseLogMarkerRule parses a rule of the form "[type:]pattern", e.g. "begin:POWER_MARK begin=(\w+)" or
+// "POWER_MARK (?P<type>begin|end)=(?P<label>\w+)". Without a type prefix, the pattern must contain a "type" group
+// that matches "begin", "end" or "event". The label is taken from the "label" group, the first group other than the
+// "type" group, or the whole match, in this order.
+func ParseLogMarkerRule(s string) (LogMarkerRule, error) {
+	var r LogMarkerRule
+
+	// Split type
+	if prefix, pattern, ok := strings.Cut(s, ":"); ok && validMarkerType(MarkerType(prefix)) {
+		r.Type, s = MarkerType(prefix), pattern
+	}
+
+	// Compile pattern
+	pattern, err := regexp.Compile(s)
+	if err != nil {
+		return LogMarkerRule{}, fmt.Errorf("invalid log marker rule %q: %w", s, err)
+	}
+
+	if (r.Type == "") && (pattern.SubexpIndex("type") < 0) {
+		return LogMarkerRule{}, fmt.Errorf("invalid log marker rule %q: expected type prefix or \"type\" group", s)
+	}
+
+	r.Pattern = pattern
+
+	return r, nil
+}
+
+// ParseLogMarkerRules parses a list of rules.
+func ParseLogMarkerRules(ss []string) ([]LogMarkerRule, error) {
+	rules := make([]LogMarkerRule, 0, len(ss))
+
+	for _, s := range ss {
+		r, err := ParseLogMarkerRule(s)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Marker returns the marker for a log line, and false if the line doesn't match the rule.
+func (r *LogMarkerRule) Marker(l *LogLine) (Marker, bool) {
+	match := r.Pattern.FindStringSubmatch(l.Message)
+	if match == nil {
+		return Marker{}, false
+	}
+
+	// Type
+	typ := r.Type
+
+	if i := r.Pattern.SubexpIndex("type"); i >= 0 {
+		typ = MarkerType(strings.ToLower(match[i]))
+	}
+
+	if !validMarkerType(typ) {
+		return Marker{}, false
+	}
+
+	// Label
+	label := match[0]
+
+	if i := r.Pattern.SubexpIndex("label"); i >= 0 {
+		label = match[i]
+	} else {
+		// First group, other than the type group
+		for i, name := range r.Pattern.SubexpNames() {
+			if (i > 0) && (name != "type") {
+				label = match[i]
+				break
+			}
+		}
+	}
+
+	return Marker{Time: l.Time, Type: typ, Label: label}, true
+}
+
+// validMarkerType returns true if the marker type is known.
+func validMarkerType(typ MarkerType) bool {
+	switch typ {
+	case MarkerTypeBegin, MarkerTypeEnd, MarkerTypeEvent:
+		return true
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,147 @@
+package powerhouse
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+const (
+	// lockStateNotification is sent whenever the device is locked or unlocked.
+	lockStateNotification = "com.apple.springboard.lockstate"
+
+	// lockCompleteNotification is only sent when the device is locked, right before lockStateNotification.
+	lockCompleteNotification = "com.apple.springboard.lockcomplete"
+)
+
+// notificationLabels are the labels of event markers for well-known notifications. Other notifications are labeled
+// by their name.
+var notificationLabels = map[string]string{
+	lockCompleteNotification:                   "device locked",
+	"com.apple.mobile.application_installed":   "app installed",
+	"com.apple.mobile.application_uninstalled": "app uninstalled",
+	"com.apple.mobile.backup.domain_changed":   "backup settings changed",
+	"com.apple.itunes-mobdev.syncWillStart":    "sync started",
+	"com.apple.itunes-mobdev.syncDidFinish":    "sync finished",
+	"com.apple.mobile.developer_image_mounted": "developer disk image mounted",
+	"com.apple.mobile.lockdown.host_attached":  "host attached",
+	"com.apple.mobile.lockdown.host_detached":  "host detached",
+	"com.apple.language.changed":               "language changed",
+}
+
+// DefaultNotifications are the notifications observed by default: lock and unlock, app installs, changes of the backup
+// settings, and syncs, which include backups.
+var DefaultNotifications = []string{
+	lockStateNotification,
+	"com.apple.mobile.application_installed",
+	"com.apple.mobile.application_uninstalled",
+	"com.apple.mobile.backup.domain_changed",
+	"com.apple.itunes-mobdev.syncWillStart",
+	"com.apple.itunes-mobdev.syncDidFinish",
+}
+
+// notificationLabel returns the label of the event marker for a notification.
+func notificationLabel(name string) string {
+	if label, ok := notificationLabels[name]; ok {
+		return label
+	}
+
+	return name
+}
+
+// reportNotifications starts the notification proxy, and returns a function that sends an event marker for each of
+// the notifications with the given names to the given channel, until the context is canceled. The lock state
+// notification is labeled as lock or unlock, depending on whether the lock complete notification came right before.
+func (dev *Device) reportNotifications(ctx context.Context, names []string, metrics chan<- *Metrics) (func(), error) {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start notification proxy, and observe
+	npc, err := lds.StartNotificationProxyService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start notification proxy service: %w", err)
+	}
+
+	// Lock complete is needed to tell locks from unlocks, but marked as part of the lock state
+	lockState := slices.Contains(names, lockStateNotification)
+
+	if lockState && !slices.Contains(names, lockCompleteNotification) {
+		names = append(slices.Clone(names), lockCompleteNotification)
+	}
+
+	if err := npc.Observe(names...); err != nil {
+		npc.Close()
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("observe notifications: %w", err)
+	}
+
+	// Return function that streams until canceled
+	run := func() {
+		// Read notifications in the background, until the client is closed
+		notifications := readInBackground(npc.ReadNotification)
+
+		// Whether the device was locked since the last lock state notification
+		lockCompleted := false
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop
+				break loop
+
+			case name, ok := <-notifications.values:
+				if !ok {
+					metrics <- &Metrics{Err: fmt.Errorf("read notification: %w", notifications.err())}
+					break loop
+				}
+
+				// The lock state notification is sent on both lock and unlock
+				label := notificationLabel(name)
+
+				switch {
+				case lockState && (name == lockCompleteNotification):
+					lockCompleted = true
+					continue
+
+				case name == lockStateNotification:
+					label = "device unlocked"
+					if lockCompleted {
+						label = "device locked"
+					}
+
+					lockCompleted = false
+				}
+
+				// Send out
+				m := Marker{Time: time.Now(), Type: MarkerTypeEvent, Label: label}
+				metrics <- &Metrics{Marker: &m}
+			}
+		}
+
+		// Clean up, and drain notifications until the reader stopped
+		npc.Close()
+		lds.Close()
+		ldc.Close()
+
+		notifications.drain()
+	}
+
+	return run, nil
+}
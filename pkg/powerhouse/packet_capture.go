@@ -0,0 +1,125 @@
+package powerhouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+	"github.com/crissyfield/powerhouse/internal/pcapng"
+)
+
+// reportPacketCapture starts the packet capture, and returns a function that writes all packets as pcapng file to the
+// given writer, and sends the captured traffic to the given channel every interval (if there was any), until the context
+// is canceled.
+func (dev *Device) reportPacketCapture(
+	ctx context.Context,
+	interval time.Duration,
+	w io.Writer,
+	metrics chan<- *Metrics,
+) (func(), error) {
+	// Write file header
+	pw, err := pcapng.NewWriter(w, "powerhouse", "iOS")
+	if err != nil {
+		return nil, fmt.Errorf("create pcapng writer: %w", err)
+	}
+
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start packet capture
+	pc, err := lds.StartPcapdService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start pcapd service: %w", err)
+	}
+
+	// Return function that streams until canceled
+	run := func() {
+		// Read packets in the background, until the client is closed
+		packets := readInBackground(pc.ReadPacket)
+
+		// Traffic is summed up over each interval
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		traffic := &NetworkMetrics{}
+		last := time.Now()
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop
+				break loop
+
+			case p, ok := <-packets.values:
+				if !ok {
+					metrics <- &Metrics{Err: fmt.Errorf("read packet: %w", packets.err())}
+					break loop
+				}
+
+				// Write packet
+				if err := pw.WritePacket(p.Time, p.Frame, p.Length, packetComment(p)); err != nil {
+					metrics <- &Metrics{Err: fmt.Errorf("write packet: %w", err)}
+					break loop
+				}
+
+				// Sum up traffic
+				if p.Outgoing {
+					traffic.BytesOut += uint64(p.Length)
+					traffic.PacketsOut++
+				} else {
+					traffic.BytesIn += uint64(p.Length)
+					traffic.PacketsIn++
+				}
+
+			case now := <-ticker.C:
+				// Send out traffic of the interval
+				if (traffic.PacketsIn > 0) || (traffic.PacketsOut > 0) {
+					traffic.Time, traffic.Interval = now, now.Sub(last).Seconds()
+					metrics <- &Metrics{Capture: traffic}
+					traffic = &NetworkMetrics{}
+				}
+
+				last = now
+			}
+		}
+
+		// Clean up, and drain packets until the reader stopped
+		pc.Close()
+		lds.Close()
+		ldc.Close()
+
+		packets.drain()
+	}
+
+	return run, nil
+}
+
+// packetComment describes the process and interface of a packet, e.g. "nsurlsessiond[123] for MyApp[456], en0, out".
+func packetComment(p *idevice.Packet) string {
+	direction := "in"
+	if p.Outgoing {
+		direction = "out"
+	}
+
+	if (p.EffectivePID > 0) && (p.EffectivePID != p.PID) {
+		return fmt.Sprintf("%s[%d] for %s[%d], %s, %s",
+			p.Process, p.PID, p.EffectiveProcess, p.EffectivePID, p.Interface, direction)
+	}
+
+	return fmt.Sprintf("%s[%d], %s, %s", p.Process, p.PID, p.Interface, direction)
+}
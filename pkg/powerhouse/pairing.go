@@ -0,0 +1,106 @@
+package powerhouse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+var (
+	// ErrPasswordProtected is returned if the device is locked with a passcode and must be unlocked first.
+	ErrPasswordProtected = idevice.ErrPasswordProtected
+
+	// ErrPairingDialogResponsePending is returned if the user did not respond to the pairing dialog on the device (in
+	// time).
+	ErrPairingDialogResponsePending = idevice.ErrPairingDialogResponsePending
+
+	// ErrUserDeniedPairing is returned if the user denied the pairing request on the device.
+	ErrUserDeniedPairing = idevice.ErrUserDeniedPairing
+
+	// ErrInvalidHostID is returned if the device does not accept the pair record of the host.
+	ErrInvalidHostID = idevice.ErrInvalidHostID
+)
+
+// Pair pairs the host with the device and saves the new pair record to the pair record store. The user has to tap
+// "Trust" on the device, which is waited for until the context is canceled.
+func (dev *Device) Pair(ctx context.Context) error {
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	defer ldc.Close()
+
+	// Pair
+	pairRecord, err := ldc.Pair(ctx)
+	if err != nil {
+		return fmt.Errorf("pair: %w", err)
+	}
+
+	// Save pair record
+	err = dev.idev.SavePairRecord(pairRecord)
+	if err != nil {
+		return fmt.Errorf("save pair record: %w", err)
+	}
+
+	// Validate saved pair record
+	return dev.ValidatePair()
+}
+
+// ValidatePair validates the stored pair record against the device.
+func (dev *Device) ValidatePair() error {
+	// Read pair record
+	pairRecord, err := dev.idev.ReadPairRecord()
+	if err != nil {
+		return fmt.Errorf("read pair record: %w", err)
+	}
+
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	defer ldc.Close()
+
+	// Validate
+	err = ldc.ValidatePair(pairRecord)
+	if err != nil {
+		return fmt.Errorf("validate pair record: %w", err)
+	}
+
+	return nil
+}
+
+// Unpair removes the pair record from the device, and deletes it from the pair record store.
+func (dev *Device) Unpair() error {
+	// Read pair record
+	pairRecord, err := dev.idev.ReadPairRecord()
+	if err != nil {
+		return fmt.Errorf("read pair record: %w", err)
+	}
+
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	defer ldc.Close()
+
+	// Unpair
+	err = ldc.Unpair(pairRecord)
+	if err != nil {
+		return fmt.Errorf("unpair: %w", err)
+	}
+
+	// Delete pair record
+	err = dev.idev.DeletePairRecord()
+	if err != nil {
+		return fmt.Errorf("delete pair record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package powerhouse
+
+import (
+	"fmt"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// Powerhouse is the main object of the powerhouse package.
+type Powerhouse struct {
+	source deviceSource // Source of devices (usbmuxd or direct network)
+}
+
+// deviceSource lists connected devices.
+type deviceSource interface {
+	Devices() ([]*idevice.Device, error)
+}
+
+// Config contains the configuration of a Powerhouse object.
+type Config struct {
+	// USBMuxAddress is the address of usbmuxd, either "unix:/path/to/socket" or "tcp:host:port". If empty, the
+	// USBMUXD_SOCKET_ADDRESS environment variable or the platform's default socket is used.
+	USBMuxAddress string
+
+	// PairRecords is the path to a directory containing "<UDID>.plist" pair record files, or to a single pair record
+	// file. Pair records that can't be found there are read from usbmuxd. If empty, only usbmuxd is used (or the
+	// platform's default pair record directory for direct network connections).
+	PairRecords string
+
+	// Hosts are the addresses of network devices to connect to directly, without usbmuxd.
+	Hosts []string
+
+	// Browse enables discovery of network devices via mDNS, to connect to them directly, without usbmuxd.
+	Browse bool
+}
+
+// New creates a new Powerhouse object.
+func New(cfg Config) (*Powerhouse, error) {
+	// Create pair record store
+	var pairRecords idevice.PairRecordStore
+
+	if cfg.PairRecords != "" {
+		store, err := idevice.NewPairRecordStore(cfg.PairRecords)
+		if err != nil {
+			return nil, fmt.Errorf("create pair record store: %w", err)
+		}
+
+		pairRecords = store
+	}
+
+	// Create direct network connection, which doesn't use usbmuxd at all
+	if (len(cfg.Hosts) > 0) || cfg.Browse {
+		if pairRecords == nil {
+			pairRecords = idevice.NewDefaultPairRecordStore()
+		}
+
+		return &Powerhouse{source: idevice.NewNetwork(cfg.Hosts, cfg.Browse, pairRecords)}, nil
+	}
+
+	// Create USB mux
+	mux, err := idevice.NewUSBMux(cfg.USBMuxAddress, pairRecords)
+	if err != nil {
+		return nil, fmt.Errorf("create USBmux: %w", err)
+	}
+
+	return &Powerhouse{source: mux}, nil
+}
+
+// Devices returns all connected devices. isUSB and isNetwork select the allowed connection types.
+func (c *Powerhouse) Devices(isUSB bool, isNetwork bool) ([]*Device, error) {
+	// Get list of connected devices
+	idevs, err := c.source.Devices()
+	if err != nil {
+		return nil, fmt.Errorf("get list of connected devices: %w", err)
+	}
+
+	// ...
+	devices := make([]*Device, 0, len(idevs))
+
+	for _, idev := range idevs {
+		// Filter
+		if !isUSB && (idev.ConnectionType() == "USB") {
+			continue
+		}
+
+		if !isNetwork && (idev.ConnectionType() == "Network") {
+			continue
+		}
+
+		// Create device
+		device, err := newDevice(idev)
+		if err != nil {
+			return nil, fmt.Errorf("create device: %w", err)
+		}
+
+		// Append
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// Device returns the connected device with the given UDID, or the first connected device if the UDID is empty. isUSB
+// and isNetwork select the allowed connection types. ErrDeviceNotFound is returned if there is no such device.
+func (c *Powerhouse) Device(udid string, isUSB bool, isNetwork bool) (*Device, error) {
+	// Get list of connected devices
+	devices, err := c.Devices(isUSB, isNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	// Select device
+	for _, dev := range devices {
+		if (udid == "") || (dev.UDID == udid) {
+			return dev, nil
+		}
+	}
+
+	if udid != "" {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, udid)
+	}
+
+	return nil, fmt.Errorf("%w: no device connected", ErrDeviceNotFound)
+}
@@ -0,0 +1,123 @@
+package powerhouse
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// processAttributes are the sysmontap attributes read for each process, in this order.
+var processAttributes = []string{"pid", "name", "cpuUsage", "intWakeups", "platIdleWakeups", "powerScore"}
+
+// ProcessesMetrics contains the resource usage of all busy processes during an interval.
+type ProcessesMetrics struct {
+	// Time of the sample.
+	Time time.Time
+
+	// Length of the interval ending with the sample (in s).
+	Interval float64
+
+	// Processes that used the CPU or woke up during the interval.
+	Processes []ProcessMetrics
+}
+
+// ProcessMetrics contains the resource usage of a process during an interval.
+type ProcessMetrics struct {
+	PID  int
+	Name string
+
+	// CPU usage (in % of one core), and CPU time used during the interval (in s).
+	CPUUsage float64
+	CPUTime  float64
+
+	// Number of interrupt and package idle wakeups.
+	Wakeups     uint64
+	IdleWakeups uint64
+
+	// Energy impact, as estimated by the device (unitless, like in Xcode's energy gauge).
+	EnergyImpact float64
+}
+
+// processesMetricsFromSysmontap parses the processes of a sysmontap sample covering the given interval (in s), and
+// updates the cumulative wakeup counters. It returns false if the sample contains no processes.
+func processesMetricsFromSysmontap(sample map[string]any, interval float64, wakeups map[int][2]uint64) (
+	[]ProcessMetrics, bool,
+) {
+	procs, ok := sample["Processes"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	var processes []ProcessMetrics
+
+	seen := make(map[int]bool, len(procs))
+
+	for key, v := range procs {
+		attrs, ok := v.([]any)
+		if !ok || (len(attrs) < len(processAttributes)) {
+			continue
+		}
+
+		pid, err := strconv.Atoi(key)
+		if err != nil {
+			pid = int(number(attrs[0]))
+		}
+
+		seen[pid] = true
+
+		// Wakeups since the previous sample
+		counters := [2]uint64{uint64(number(attrs[3])), uint64(number(attrs[4]))}
+		prev, known := wakeups[pid]
+		wakeups[pid] = counters
+
+		p := ProcessMetrics{PID: pid, CPUUsage: number(attrs[2]), EnergyImpact: number(attrs[5])}
+		p.Name, _ = attrs[1].(string)
+		p.CPUTime = p.CPUUsage / 100.0 * interval
+
+		if known && (counters[0] >= prev[0]) && (counters[1] >= prev[1]) {
+			p.Wakeups = counters[0] - prev[0]
+			p.IdleWakeups = counters[1] - prev[1]
+		}
+
+		// Skip idle processes
+		if (p.CPUUsage <= 0) && (p.EnergyImpact <= 0) && (p.Wakeups == 0) && (p.IdleWakeups == 0) {
+			continue
+		}
+
+		processes = append(processes, p)
+	}
+
+	// Forget processes that exited
+	for pid := range wakeups {
+		if !seen[pid] {
+			delete(wakeups, pid)
+		}
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+
+	return processes, true
+}
+
+// number converts a numeric value reported by the device to float64, and returns 0 for anything else.
+func number(v any) float64 {
+	switch n := v.(type) {
+	case uint64:
+		return float64(n)
+
+	case int64:
+		return float64(n)
+
+	case int32:
+		return float64(n)
+
+	case float64:
+		return n
+
+	case float32:
+		return float64(n)
+
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,222 @@
+package powerhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// recordingFileVersion is the version of the recording file format written by WriteFile.
+const recordingFileVersion = 1
+
+// MarkerType defines how a marker is interpreted.
+type MarkerType string
+
+const (
+	// MarkerTypeBegin marks the beginning of a phase.
+	MarkerTypeBegin MarkerType = "begin"
+
+	// MarkerTypeEnd marks the end of a phase.
+	MarkerTypeEnd MarkerType = "end"
+
+	// MarkerTypeEvent marks a single point in time.
+	MarkerTypeEvent MarkerType = "event"
+)
+
+// Marker labels a point in time of a recording. Pairs of begin and end markers with the same label define a phase.
+type Marker struct {
+	// Time the marker was set.
+	Time time.Time
+
+	// Type of the marker.
+	Type MarkerType
+
+	// Label of the marker, or of the phase for begin and end markers.
+	Label string
+}
+
+// Metadata describes the circumstances of a recording.
+type Metadata struct {
+	// LaunchedApp is the app under test, if it was launched for the recording.
+	LaunchedApp *LaunchedApp
+
+	// Device state at the start and the end of the recording, if it was taken. The end state is missing if the
+	// device was gone by then.
+	StartState *DeviceState
+	EndState   *DeviceState
+}
+
+// Recording contains everything that was measured during a session.
+type Recording struct {
+	// Device that was measured.
+	Device *Device
+
+	// Metadata of the recording.
+	Metadata Metadata
+
+	// Start and end time of the recording. End is zero while the recording is running.
+	Start time.Time
+	End   time.Time
+
+	// Samples in the order they were received.
+	Samples []*Metrics
+
+	// Markers in the order they were set.
+	Markers []Marker
+
+	// Crashes of processes during the recording, if crash reports were collected.
+	Crashes []CrashReport
+
+	// Screenshots in the order they were taken, if screenshots were enabled.
+	Screenshots []Screenshot
+
+	// ScreenshotDir is the directory the file names of screenshots are relative to: MetricsOptions.ScreenshotDir
+	// while recording, or the directory of the recording file once it was read from a file.
+	ScreenshotDir string `json:"-"`
+}
+
+// recordingFile is the layout of a recording file.
+type recordingFile struct {
+	Version int
+	*Recording
+}
+
+// RecordingScreenshotDir returns the directory next to a recording file that WriteFile copies screenshots to, named
+// after the file with the extension ".screenshots". Screenshots taken into this directory aren't copied.
+func RecordingScreenshotDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".screenshots"
+}
+
+// WriteFile writes the recording to a file (usually with the extension ".phrec"). Screenshots are copied as PNG files
+// to a directory next to it, see RecordingScreenshotDir.
+func (rec *Recording) WriteFile(path string) error {
+	// Optionally, write screenshots
+	if len(rec.Screenshots) > 0 {
+		screenshots, err := rec.writeScreenshots(RecordingScreenshotDir(path))
+		if err != nil {
+			return fmt.Errorf("write screenshots: %w", err)
+		}
+
+		cp := *rec
+		cp.Screenshots = screenshots
+		rec = &cp
+	}
+
+	// Create file
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create recording file: %w", err)
+	}
+
+	defer f.Close()
+
+	// Encode
+	err = json.NewEncoder(f).Encode(recordingFile{Version: recordingFileVersion, Recording: rec})
+	if err != nil {
+		return fmt.Errorf("encode recording: %w", err)
+	}
+
+	return f.Close()
+}
+
+// ReadRecordingFile reads a recording from a file written by WriteFile.
+func ReadRecordingFile(path string) (*Recording, error) {
+	// Open file
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording file: %w", err)
+	}
+
+	defer f.Close()
+
+	// Decode
+	rf := recordingFile{Recording: &Recording{}}
+
+	err = json.NewDecoder(f).Decode(&rf)
+	if err != nil {
+		return nil, fmt.Errorf("decode recording: %w", err)
+	}
+
+	if rf.Version > recordingFileVersion {
+		return nil, fmt.Errorf("unsupported recording file version %d", rf.Version)
+	}
+
+	rf.Recording.ScreenshotDir = filepath.Dir(path)
+
+	return rf.Recording, nil
+}
+
+// ScreenshotData returns the PNG image of a screenshot of the recording.
+func (rec *Recording) ScreenshotData(s *Screenshot) ([]byte, error) {
+	data, err := os.ReadFile(rec.screenshotPath(s))
+	if err != nil {
+		return nil, fmt.Errorf("read screenshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// screenshotPath returns the path of the file of a screenshot of the recording.
+func (rec *Recording) screenshotPath(s *Screenshot) string {
+	return filepath.Join(rec.ScreenshotDir, filepath.FromSlash(s.File))
+}
+
+// writeScreenshots copies the screenshots to the given directory, unless they were taken into it, and returns them
+// with file names relative to the directory of the recording file.
+func (rec *Recording) writeScreenshots(dir string) ([]Screenshot, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	screenshots := make([]Screenshot, 0, len(rec.Screenshots))
+
+	for _, s := range rec.Screenshots {
+		name := filepath.Base(filepath.FromSlash(s.File))
+
+		if err := copyFile(rec.screenshotPath(&s), filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("copy %s: %w", name, err)
+		}
+
+		screenshots = append(screenshots, Screenshot{Time: s.Time, File: filepath.Base(dir) + "/" + name})
+	}
+
+	return screenshots, nil
+}
+
+// copyFile copies a file, unless source and destination are the same file.
+func copyFile(src string, dst string) error {
+	// Open source
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	srcInfo, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if dstInfo, err := os.Stat(dst); (err == nil) && os.SameFile(srcInfo, dstInfo) {
+		return nil
+	}
+
+	// Copy to destination
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
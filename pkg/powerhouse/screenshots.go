@@ -0,0 +1,146 @@
+package powerhouse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/image/tiff"
+
+	"github.com/crissyfield/powerhouse/internal/idevice"
+)
+
+// pngSignature starts every PNG image.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Screenshot is a screenshot taken during a recording.
+type Screenshot struct {
+	// Time the screenshot was taken.
+	Time time.Time
+
+	// File name of the PNG image, indexed by time, relative to the screenshot directory of the recording.
+	File string
+}
+
+// reportScreenshots starts the screenshot service, and returns a function that takes a screenshot right away and then
+// every interval, until the context is canceled. Screenshots are written to the given directory, and sent to the given
+// channel by file name. Screenshots that can't be taken are logged and skipped. This requires the developer disk image
+// to be mounted.
+func (dev *Device) reportScreenshots(
+	ctx context.Context,
+	interval time.Duration,
+	dir string,
+	metrics chan<- *Metrics,
+) (func(), error) {
+	// Create screenshot directory
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint
+		return nil, fmt.Errorf("create screenshot directory: %w", err)
+	}
+
+	// Create lockdown client
+	ldc, err := idevice.NewLockdownClient(dev.idev)
+	if err != nil {
+		return nil, fmt.Errorf("create lockdown client: %w", err)
+	}
+
+	// Start lockdown session
+	lds, err := ldc.StartSession()
+	if err != nil {
+		ldc.Close()
+		return nil, fmt.Errorf("start lockdown session: %w", err)
+	}
+
+	// Start screenshot service
+	sc, err := lds.StartScreenshotService()
+	if err != nil {
+		lds.Close()
+		ldc.Close()
+		return nil, fmt.Errorf("start screenshot service: %w", err)
+	}
+
+	// Return function that takes screenshots until canceled
+	run := func() {
+		defer func() {
+			sc.Close()
+			lds.Close()
+			ldc.Close()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// Take screenshot
+			now := time.Now()
+
+			data, err := takeScreenshot(sc)
+			if err != nil {
+				slog.Warn("Unable to take screenshot", slog.String("udid", dev.UDID), slog.Any("error", err))
+			} else {
+				// Write and send out
+				name := screenshotFile(now)
+
+				if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil { //nolint
+					metrics <- &Metrics{Err: fmt.Errorf("write screenshot: %w", err)}
+					return
+				}
+
+				metrics <- &Metrics{Screenshot: &Screenshot{Time: now, File: name}}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+			}
+		}
+	}
+
+	return run, nil
+}
+
+// takeScreenshot takes a screenshot as PNG image.
+func takeScreenshot(sc *idevice.ScreenshotClient) ([]byte, error) {
+	data, err := sc.TakeScreenshot()
+	if err != nil {
+		return nil, fmt.Errorf("take screenshot: %w", err)
+	}
+
+	data, err = screenshotPNG(data)
+	if err != nil {
+		return nil, fmt.Errorf("convert screenshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// screenshotPNG converts a screenshot to PNG, as older devices take screenshots as TIFF images.
+func screenshotPNG(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, pngSignature) {
+		return data, nil
+	}
+
+	img, err := tiff.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode TIFF image: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode PNG image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// screenshotFile returns the file name of a screenshot taken at the given time, e.g. "20261018T123456.789Z.png".
+func screenshotFile(t time.Time) string {
+	return t.UTC().Format("20060102T150405.000Z") + ".png"
+}
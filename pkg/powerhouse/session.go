@@ -0,0 +1,325 @@
+package powerhouse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionOptions configures a measurement session.
+type SessionOptions struct {
+	// Duration is the max duration of the session, or 0 for no limit.
+	Duration time.Duration
+
+	// Metrics enables optional collectors.
+	Metrics MetricsOptions
+
+	// Apps are the bundle IDs of apps whose versions are recorded in the device state at the start and the end.
+	Apps []string
+
+	// LaunchedApp is the app under test, if it was launched before the session. It is recorded in the metadata, and
+	// its version in the device state. If KillApp is set, it is killed once the session is done.
+	LaunchedApp *LaunchedApp
+	KillApp     bool
+
+	// OnMetrics is called with all metrics once they are recorded, in the order they are reported, including the
+	// error that ends the session prematurely. Unlike with Subscribe, nothing is dropped, as the session waits for
+	// OnMetrics to return.
+	OnMetrics func(m *Metrics)
+}
+
+// Session measures a device until it is stopped, and records all metrics and markers.
+type Session struct {
+	// ID uniquely identifies the session.
+	ID string
+
+	// Function to stop reporting metrics
+	cancel context.CancelFunc
+
+	// Closed once the session is done
+	done chan struct{}
+
+	// Apps recorded in the device state, and the app to kill once done
+	apps    []string
+	killApp bool
+
+	// Function called with all metrics
+	onMetrics func(m *Metrics)
+
+	// Recorded data, guarded by mu
+	mu          sync.Mutex
+	recording   Recording
+	err         error
+	subscribers map[chan *Metrics]struct{}
+
+	// Energy drawn until the last battery sample, guarded by mu
+	energy      float64
+	lastBattery *BatteryMetrics
+}
+
+// StartSession starts a new measurement session on the device.
+func (dev *Device) StartSession(opts SessionOptions) (*Session, error) {
+	// Create session ID
+	id := make([]byte, 8)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("create session ID: %w", err)
+	}
+
+	// Take device state, including the version of the launched app
+	apps := opts.Apps
+
+	if (opts.LaunchedApp != nil) && !slices.Contains(apps, opts.LaunchedApp.BundleID) {
+		apps = append(slices.Clone(apps), opts.LaunchedApp.BundleID)
+	}
+
+	state, err := dev.State(apps)
+	if err != nil {
+		return nil, fmt.Errorf("take device state: %w", err)
+	}
+
+	// Start reporting metrics
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if opts.Duration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.Duration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	metrics, err := dev.ReportMetrics(ctx, opts.Metrics)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("start metrics: %w", err)
+	}
+
+	// Create session
+	s := &Session{
+		ID:        hex.EncodeToString(id),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		apps:      apps,
+		killApp:   opts.KillApp,
+		onMetrics: opts.OnMetrics,
+		recording: Recording{
+			Device:        dev,
+			Metadata:      Metadata{LaunchedApp: opts.LaunchedApp, StartState: state},
+			Start:         time.Now(),
+			ScreenshotDir: opts.Metrics.ScreenshotDir,
+		},
+		subscribers: make(map[chan *Metrics]struct{}),
+	}
+
+	go s.run(dev, metrics)
+
+	return s, nil
+}
+
+// Mark adds a marker of the given type and label to the session, and returns it.
+func (s *Session) Mark(typ MarkerType, label string) Marker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Append marker
+	m := Marker{Time: time.Now(), Type: typ, Label: label}
+	s.recording.Markers = append(s.recording.Markers, m)
+
+	return m
+}
+
+// Stop stops the session and waits until it is done.
+func (s *Session) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Done returns a channel that is closed once the session is done.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the session prematurely, if any.
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Recording returns a snapshot of everything recorded so far.
+func (s *Session) Recording() *Recording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Copy recording
+	rec := s.recording
+	rec.Samples = append([]*Metrics(nil), s.recording.Samples...)
+	rec.Markers = append([]Marker(nil), s.recording.Markers...)
+	rec.Crashes = append([]CrashReport(nil), s.recording.Crashes...)
+	rec.Screenshots = append([]Screenshot(nil), s.recording.Screenshots...)
+
+	return &rec
+}
+
+// Energy returns the energy drawn from the battery so far (in Wh), as in the summary. Unlike Summary, it doesn't
+// recompute anything, so it can be called for every sample.
+func (s *Session) Energy() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	energy := s.energy
+
+	if !s.recording.End.IsZero() {
+		energy += s.energyUntil(s.recording.End)
+	}
+
+	return energy
+}
+
+// energyUntil returns the energy drawn from the last battery sample until t, assuming constant power. Energy before
+// the start of the session isn't counted. Must be called with mu held.
+func (s *Session) energyUntil(t time.Time) float64 {
+	if s.lastBattery == nil {
+		return 0
+	}
+
+	from := s.lastBattery.Time
+	if from.Before(s.recording.Start) {
+		from = s.recording.Start
+	}
+
+	if !t.After(from) {
+		return 0
+	}
+
+	return s.lastBattery.Power() * t.Sub(from).Hours()
+}
+
+// Summary computes statistics of everything recorded so far.
+func (s *Session) Summary() *Summary {
+	return s.Recording().Summary()
+}
+
+// Subscribe returns a channel that receives all subsequent samples, and a function to unsubscribe. Samples are
+// dropped for subscribers that don't keep up. The error that ends the session prematurely is sent as well, and the
+// channel is closed once the session is done.
+func (s *Session) Subscribe() (<-chan *Metrics, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan *Metrics, 16)
+
+	// Session is already done
+	if s.subscribers == nil {
+		close(ch)
+		return ch, func() {}
+	}
+
+	s.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// run records metrics until the metrics channel is closed.
+func (s *Session) run(dev *Device, metrics <-chan *Metrics) {
+	for m := range metrics {
+		if s.record(m) && (s.onMetrics != nil) {
+			s.onMetrics(m)
+		}
+	}
+
+	// Finish, taking the device state unless the device is gone
+	state, _ := dev.State(s.apps)
+
+	// Optionally, kill the launched app
+	launched := s.recording.Metadata.LaunchedApp
+	killed := false
+
+	if s.killApp && (launched != nil) {
+		if err := dev.KillProcess(launched.PID); err != nil {
+			slog.Warn("Unable to kill app", slog.String("udid", dev.UDID), slog.Int("pid", launched.PID),
+				slog.Any("error", err))
+		} else {
+			killed = true
+		}
+	}
+
+	s.mu.Lock()
+
+	s.recording.End = time.Now()
+	s.recording.Metadata.EndState = state
+
+	if killed {
+		launched.Killed = true
+	}
+
+	for ch := range s.subscribers {
+		close(ch)
+	}
+
+	s.subscribers = nil
+
+	s.mu.Unlock()
+
+	close(s.done)
+}
+
+// record records and publishes metrics. It returns false for errors after the first one, which aren't published.
+func (s *Session) record(m *Metrics) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if m.Err != nil {
+		// Stop on first error
+		if s.err != nil {
+			return false
+		}
+
+		s.err = m.Err
+		s.cancel()
+	} else {
+		// Record sample. Markers from the device log, crash reports and screenshots aren't samples
+		switch {
+		case m.Marker != nil:
+			s.recording.Markers = append(s.recording.Markers, *m.Marker)
+
+		case m.Crash != nil:
+			s.recording.Crashes = append(s.recording.Crashes, *m.Crash)
+
+		case m.Screenshot != nil:
+			s.recording.Screenshots = append(s.recording.Screenshots, *m.Screenshot)
+
+		default:
+			s.recording.Samples = append(s.recording.Samples, m)
+
+			if m.Battery != nil {
+				s.energy += s.energyUntil(m.Battery.Time)
+				s.lastBattery = m.Battery
+			}
+		}
+	}
+
+	// Publish
+	for ch := range s.subscribers {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,657 @@
+package powerhouse
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Traffic bursts are intervals between battery samples with at least this many bytes of captured traffic.
+const (
+	burstBytes = 64 * 1024
+	maxBursts  = 10
+)
+
+// Summary contains statistics of a recording. Power and current are positive when discharging.
+type Summary struct {
+	// Start and end time of the summarized recording.
+	Start time.Time
+	End   time.Time
+
+	// Duration of the recording (in s).
+	Duration float64
+
+	// Number of samples.
+	Samples int
+
+	// Energy drawn from the battery (in Wh).
+	Energy float64
+
+	// Average and peak power (in W).
+	AveragePower float64
+	PeakPower    float64
+
+	// Average current (in A).
+	AverageCurrent float64
+
+	// Battery capacity at the first and the last sample (0 - 100%).
+	StartCapacity int
+	EndCapacity   int
+
+	// Average display brightness (as reported by the backlight).
+	AverageBrightness float64
+
+	// Average system-wide CPU load, GPU utilization and memory pressure (0 - 100%), if collected.
+	AverageCPULoad        float64
+	AverageGPUUtilization float64
+	AverageMemoryPressure float64
+
+	// Network traffic (in bytes), if collected.
+	NetworkBytesIn  uint64
+	NetworkBytesOut uint64
+
+	// Captured traffic, if packets were captured.
+	Traffic *TrafficSummary
+
+	// Invalid is true if a process crashed during the recording, which makes its numbers meaningless from the first
+	// crash on.
+	Invalid bool
+	Crashes []CrashSummary
+
+	// Phases defined by begin and end markers.
+	Phases []PhaseSummary
+
+	// Processes ranked by their estimated energy, if process metrics were collected.
+	Processes []ProcessSummary
+}
+
+// PhaseSummary contains statistics of a phase.
+type PhaseSummary struct {
+	// Label of the phase.
+	Label string
+
+	// Start and end time of the phase.
+	Start time.Time
+	End   time.Time
+
+	// Duration of the phase (in s).
+	Duration float64
+
+	// Number of samples taken during the phase.
+	Samples int
+
+	// Energy drawn from the battery during the phase (in Wh).
+	Energy float64
+
+	// Average power during the phase (in W).
+	AveragePower float64
+
+	// Captured traffic during the phase (in bytes), and energy per MB of traffic (in Wh/MB), if packets were
+	// captured.
+	TrafficBytes uint64
+	EnergyPerMB  float64
+
+	// Invalid is true if the phase ended after a process crashed.
+	Invalid bool
+}
+
+// CrashSummary describes a crash during a recording.
+type CrashSummary struct {
+	Time    time.Time
+	Process string
+	File    string
+}
+
+// TrafficSummary contains statistics of the captured traffic, and how it relates to the current.
+type TrafficSummary struct {
+	// Bytes and packets received and sent.
+	BytesIn    uint64
+	BytesOut   uint64
+	PacketsIn  uint64
+	PacketsOut uint64
+
+	// Energy of the recording per MB of traffic (in Wh/MB).
+	EnergyPerMB float64
+
+	// Correlation of traffic and current between battery samples (-1 - 1). Values close to 1 mean that current
+	// spikes coincide with traffic bursts.
+	Correlation float64
+
+	// Bursts of traffic, ranked by bytes.
+	Bursts []TrafficBurst
+}
+
+// TrafficBurst is an interval between battery samples with heavy traffic.
+type TrafficBurst struct {
+	Start time.Time
+	End   time.Time
+
+	// Captured traffic (in bytes).
+	Bytes uint64
+
+	// Current during the interval, and its excess over the median current (in A).
+	Current       float64
+	ExcessCurrent float64
+}
+
+// ProcessSummary contains statistics of a process, and its estimated share of the energy. The energy of each
+// interval is split among the busy processes by their energy impact, or by their CPU usage if the device doesn't
+// report energy impact.
+type ProcessSummary struct {
+	PID  int
+	Name string
+
+	// AppUnderTest is true for the process of the launched app.
+	AppUnderTest bool
+
+	// CPU time used (in s).
+	CPUTime float64
+
+	// Number of interrupt and package idle wakeups.
+	Wakeups     uint64
+	IdleWakeups uint64
+
+	// Average energy impact while busy.
+	EnergyImpact float64
+
+	// Estimated energy (in Wh), and share of the energy of all processes (0 - 1).
+	Energy float64
+	Share  float64
+}
+
+// Phase is a labeled time range of a recording.
+type Phase struct {
+	Label string
+	Start time.Time
+	End   time.Time
+}
+
+// Phases returns the phases defined by pairs of begin and end markers with the same label. Phases that haven't
+// ended yet end with the recording.
+func (rec *Recording) Phases() []Phase {
+	var phases []Phase
+
+	open := make(map[string]int)
+
+	for _, m := range rec.Markers {
+		switch m.Type {
+		case MarkerTypeBegin:
+			// Open phase
+			open[m.Label] = len(phases)
+			phases = append(phases, Phase{Label: m.Label, Start: m.Time})
+
+		case MarkerTypeEnd:
+			// Close phase
+			if i, ok := open[m.Label]; ok {
+				phases[i].End = m.Time
+				delete(open, m.Label)
+			}
+
+		case MarkerTypeEvent:
+			// Events don't define phases
+		}
+	}
+
+	// Close remaining phases
+	for _, i := range open {
+		phases[i].End = rec.end()
+	}
+
+	return phases
+}
+
+// Summary computes statistics of the recording.
+func (rec *Recording) Summary() *Summary {
+	start, end := rec.Start, rec.end()
+
+	s := &Summary{
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start).Seconds(),
+	}
+
+	// Sample statistics
+	var sumCurrent, sumBrightness float64
+	var numBrightness int
+
+	for _, m := range rec.Samples {
+		if m.Battery == nil {
+			continue
+		}
+
+		if s.Samples == 0 {
+			s.StartCapacity = m.Battery.CurrentCapacity
+		}
+
+		s.Samples++
+		s.EndCapacity = m.Battery.CurrentCapacity
+		sumCurrent += -m.Battery.InstantAmperage
+
+		if p := m.Battery.Power(); p > s.PeakPower {
+			s.PeakPower = p
+		}
+
+		if m.Backlight != nil {
+			sumBrightness += float64(m.Backlight.BrightnessValue)
+			numBrightness++
+		}
+	}
+
+	if s.Samples > 0 {
+		s.AverageCurrent = sumCurrent / float64(s.Samples)
+	}
+
+	if numBrightness > 0 {
+		s.AverageBrightness = sumBrightness / float64(numBrightness)
+	}
+
+	// System statistics
+	var sumCPU, sumGPU, sumMemory float64
+	var numCPU, numGPU, numMemory int
+
+	for _, m := range rec.Samples {
+		if m.CPU != nil {
+			sumCPU += m.CPU.Load()
+			numCPU++
+		}
+
+		if m.GPU != nil {
+			sumGPU += m.GPU.DeviceUtilization
+			numGPU++
+		}
+
+		if m.Memory != nil {
+			sumMemory += m.Memory.Pressure()
+			numMemory++
+		}
+
+		if m.Network != nil {
+			s.NetworkBytesIn += m.Network.BytesIn
+			s.NetworkBytesOut += m.Network.BytesOut
+		}
+	}
+
+	if numCPU > 0 {
+		s.AverageCPULoad = sumCPU / float64(numCPU)
+	}
+
+	if numGPU > 0 {
+		s.AverageGPUUtilization = sumGPU / float64(numGPU)
+	}
+
+	if numMemory > 0 {
+		s.AverageMemoryPressure = sumMemory / float64(numMemory)
+	}
+
+	// Energy
+	curve := rec.energyCurve()
+	s.Energy = curve.between(start, end)
+
+	if s.Duration > 0 {
+		s.AveragePower = s.Energy * 3600.0 / s.Duration
+	}
+
+	// Captured traffic
+	s.Traffic = rec.trafficSummary(s.Energy)
+
+	// Crashes
+	var firstCrash time.Time
+
+	for _, cr := range rec.Crashes {
+		s.Invalid = true
+		s.Crashes = append(s.Crashes, CrashSummary{Time: cr.Time, Process: cr.Process, File: cr.File})
+
+		if firstCrash.IsZero() || cr.Time.Before(firstCrash) {
+			firstCrash = cr.Time
+		}
+	}
+
+	// Phases
+	for _, ph := range rec.Phases() {
+		ps := PhaseSummary{
+			Label:    ph.Label,
+			Start:    ph.Start,
+			End:      ph.End,
+			Duration: ph.End.Sub(ph.Start).Seconds(),
+			Energy:   curve.between(ph.Start, ph.End),
+			Invalid:  s.Invalid && ph.End.After(firstCrash),
+		}
+
+		for _, m := range rec.Samples {
+			if (m.Battery != nil) && !m.Battery.Time.Before(ph.Start) && !m.Battery.Time.After(ph.End) {
+				ps.Samples++
+			}
+		}
+
+		if ps.Duration > 0 {
+			ps.AveragePower = ps.Energy * 3600.0 / ps.Duration
+		}
+
+		if s.Traffic != nil {
+			for _, m := range rec.Samples {
+				if m.Capture == nil {
+					continue
+				}
+
+				if t := m.Capture.middle(); !t.Before(ph.Start) && !t.After(ph.End) {
+					ps.TrafficBytes += m.Capture.BytesIn + m.Capture.BytesOut
+				}
+			}
+
+			ps.EnergyPerMB = energyPerMB(ps.Energy, ps.TrafficBytes)
+		}
+
+		s.Phases = append(s.Phases, ps)
+	}
+
+	// Processes
+	s.Processes = rec.processSummaries(curve)
+
+	return s
+}
+
+// processSummaries estimates the energy of each process, given the energy curve of the recording, and returns them
+// ranked by energy.
+func (rec *Recording) processSummaries(curve *energyCurve) []ProcessSummary {
+	byPID := make(map[int]*ProcessSummary)
+	busy := make(map[int]int)
+
+	var total float64
+
+	for _, m := range rec.Samples {
+		if m.Processes == nil {
+			continue
+		}
+
+		// Weigh by energy impact, or by CPU usage if there is none
+		var sumImpact, sumCPU float64
+
+		for _, p := range m.Processes.Processes {
+			sumImpact += p.EnergyImpact
+			sumCPU += p.CPUUsage
+		}
+
+		weight := func(p ProcessMetrics) float64 { return p.EnergyImpact / sumImpact }
+		if sumImpact <= 0 {
+			weight = func(p ProcessMetrics) float64 { return p.CPUUsage / sumCPU }
+		}
+
+		// Energy of the interval
+		interval := time.Duration(m.Processes.Interval * float64(time.Second))
+		energy := curve.between(m.Processes.Time.Add(-interval), m.Processes.Time)
+
+		// Accumulate
+		for _, p := range m.Processes.Processes {
+			ps, ok := byPID[p.PID]
+			if !ok {
+				ps = &ProcessSummary{PID: p.PID, Name: p.Name}
+				ps.AppUnderTest = (rec.Metadata.LaunchedApp != nil) && (rec.Metadata.LaunchedApp.PID == p.PID)
+				byPID[p.PID] = ps
+			}
+
+			ps.CPUTime += p.CPUTime
+			ps.Wakeups += p.Wakeups
+			ps.IdleWakeups += p.IdleWakeups
+			ps.EnergyImpact += p.EnergyImpact
+			busy[p.PID]++
+
+			if (sumImpact > 0) || (sumCPU > 0) {
+				e := energy * weight(p)
+				ps.Energy += e
+				total += e
+			}
+		}
+	}
+
+	// Rank
+	processes := make([]ProcessSummary, 0, len(byPID))
+
+	for _, ps := range byPID {
+		ps.EnergyImpact /= float64(busy[ps.PID])
+
+		if total > 0 {
+			ps.Share = ps.Energy / total
+		}
+
+		processes = append(processes, *ps)
+	}
+
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Energy != processes[j].Energy {
+			return processes[i].Energy > processes[j].Energy
+		}
+
+		return processes[i].CPUTime > processes[j].CPUTime
+	})
+
+	return processes
+}
+
+// trafficSummary summarizes the captured traffic, given the energy of the recording. It returns nil if no packets
+// were captured.
+func (rec *Recording) trafficSummary(energy float64) *TrafficSummary {
+	var ts *TrafficSummary
+
+	for _, m := range rec.Samples {
+		if m.Capture == nil {
+			continue
+		}
+
+		if ts == nil {
+			ts = &TrafficSummary{}
+		}
+
+		ts.BytesIn += m.Capture.BytesIn
+		ts.BytesOut += m.Capture.BytesOut
+		ts.PacketsIn += m.Capture.PacketsIn
+		ts.PacketsOut += m.Capture.PacketsOut
+	}
+
+	if ts == nil {
+		return nil
+	}
+
+	ts.EnergyPerMB = energyPerMB(energy, ts.BytesIn+ts.BytesOut)
+
+	// Intervals between battery samples
+	var samples []*BatteryMetrics
+
+	for _, m := range rec.Samples {
+		if m.Battery != nil {
+			samples = append(samples, m.Battery)
+		}
+	}
+
+	if len(samples) == 0 {
+		return ts
+	}
+
+	bytes := make([]float64, len(samples))
+	currents := make([]float64, len(samples))
+
+	for i, b := range samples {
+		currents[i] = -b.InstantAmperage
+	}
+
+	for _, m := range rec.Samples {
+		if m.Capture == nil {
+			continue
+		}
+
+		// Interval the traffic belongs to, i.e. the last sample before it
+		t := m.Capture.middle()
+		i := sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(t) }) - 1
+
+		if i >= 0 {
+			bytes[i] += float64(m.Capture.BytesIn + m.Capture.BytesOut)
+		}
+	}
+
+	ts.Correlation = correlation(bytes, currents)
+
+	// Bursts
+	sorted := append([]float64(nil), currents...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	for i, b := range samples {
+		if bytes[i] < burstBytes {
+			continue
+		}
+
+		end := rec.end()
+		if i+1 < len(samples) {
+			end = samples[i+1].Time
+		}
+
+		ts.Bursts = append(ts.Bursts, TrafficBurst{
+			Start:         b.Time,
+			End:           end,
+			Bytes:         uint64(bytes[i]),
+			Current:       currents[i],
+			ExcessCurrent: currents[i] - median,
+		})
+	}
+
+	sort.SliceStable(ts.Bursts, func(i, j int) bool { return ts.Bursts[i].Bytes > ts.Bursts[j].Bytes })
+	ts.Bursts = ts.Bursts[:min(len(ts.Bursts), maxBursts)]
+
+	return ts
+}
+
+// middle returns the middle of the interval covered by the traffic.
+func (m *NetworkMetrics) middle() time.Time {
+	return m.Time.Add(-time.Duration(m.Interval * float64(time.Second) / 2.0))
+}
+
+// energyPerMB returns the energy (in Wh) per MB of traffic, or 0 if there was no traffic.
+func energyPerMB(energy float64, bytes uint64) float64 {
+	if bytes == 0 {
+		return 0
+	}
+
+	return energy / (float64(bytes) / 1e6)
+}
+
+// correlation returns the Pearson correlation coefficient of x and y, or 0 if either is constant.
+func correlation(x []float64, y []float64) float64 {
+	n := float64(len(x))
+	if n == 0 {
+		return 0
+	}
+
+	var sumX, sumY float64
+
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+
+	if (varX == 0) || (varY == 0) {
+		return 0
+	}
+
+	return cov / math.Sqrt(varX*varY)
+}
+
+// Energy returns the energy drawn from the battery between from and to (in Wh). The power of each sample is assumed
+// to be constant until the next sample.
+func (rec *Recording) Energy(from time.Time, to time.Time) float64 {
+	return rec.energyCurve().between(from, to)
+}
+
+// energyCurve integrates the power of battery samples over time, so that the energy between any two points in time
+// can be looked up quickly. The power of each sample is assumed to be constant until the next sample.
+type energyCurve struct {
+	times  []time.Time // Time of each sample
+	powers []float64   // Power of each sample (in W)
+	sums   []float64   // Energy from the first sample until each sample (in Wh)
+	end    time.Time   // End of the last sample
+}
+
+// energyCurve creates the energy curve of the battery samples of the recording.
+func (rec *Recording) energyCurve() *energyCurve {
+	c := &energyCurve{end: rec.end()}
+
+	for _, m := range rec.Samples {
+		if m.Battery == nil {
+			continue
+		}
+
+		// Accumulate energy of the previous sample
+		sum := 0.0
+
+		if n := len(c.times); n > 0 {
+			sum = c.sums[n-1]
+
+			if d := m.Battery.Time.Sub(c.times[n-1]); d > 0 {
+				sum += c.powers[n-1] * d.Hours()
+			}
+		}
+
+		c.times = append(c.times, m.Battery.Time)
+		c.powers = append(c.powers, m.Battery.Power())
+		c.sums = append(c.sums, sum)
+	}
+
+	return c
+}
+
+// at returns the energy from the first sample until t (in Wh).
+func (c *energyCurve) at(t time.Time) float64 {
+	// Last sample not after t
+	i := sort.Search(len(c.times), func(i int) bool { return c.times[i].After(t) }) - 1
+	if i < 0 {
+		return 0
+	}
+
+	// The last sample ends at the end of the recording
+	if (i == len(c.times)-1) && t.After(c.end) {
+		t = c.end
+	}
+
+	energy := c.sums[i]
+
+	if d := t.Sub(c.times[i]); d > 0 {
+		energy += c.powers[i] * d.Hours()
+	}
+
+	return energy
+}
+
+// between returns the energy between from and to (in Wh).
+func (c *energyCurve) between(from time.Time, to time.Time) float64 {
+	if !to.After(from) {
+		return 0
+	}
+
+	return c.at(to) - c.at(from)
+}
+
+// end returns the end time of the recording, or the time of the last sample if the recording is still running.
+func (rec *Recording) end() time.Time {
+	if !rec.End.IsZero() {
+		return rec.End
+	}
+
+	end := rec.Start
+
+	for _, m := range rec.Samples {
+		if (m.Battery != nil) && m.Battery.Time.After(end) {
+			end = m.Battery.Time
+		}
+	}
+
+	return end
+}
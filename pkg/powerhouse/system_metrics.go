@@ -0,0 +1,201 @@
+package powerhouse
+
+import (
+	"time"
+)
+
+// systemAttributes are the sysmontap attributes read for the whole system, in this order.
+var systemAttributes = []string{
+	"vmFreeCount", "vmPurgeableCount", "vmSpeculativeCount", "vmActiveCount", "vmInactiveCount", "vmWireCount",
+	"vmCompressorPageCount", "physMemSize", "netBytesIn", "netBytesOut", "netPacketsIn", "netPacketsOut",
+}
+
+// CPUMetrics contains the system-wide CPU load.
+type CPUMetrics struct {
+	Time time.Time
+
+	// Number of CPU cores.
+	Cores int
+
+	// Total, user and system load (in % of one core, summed over all cores).
+	TotalLoad  float64
+	UserLoad   float64
+	SystemLoad float64
+}
+
+// Load returns the total load relative to all cores (0 - 100%).
+func (m *CPUMetrics) Load() float64 {
+	if m.Cores <= 0 {
+		return m.TotalLoad
+	}
+
+	return m.TotalLoad / float64(m.Cores)
+}
+
+// GPUMetrics contains the GPU utilization, averaged over an interval.
+type GPUMetrics struct {
+	Time time.Time
+
+	// Utilization of the whole GPU, and of its renderer and tiler (0 - 100%).
+	DeviceUtilization   float64
+	RendererUtilization float64
+	TilerUtilization    float64
+
+	// Frames per second rendered by Core Animation.
+	FramesPerSecond float64
+}
+
+// MemoryMetrics contains the system-wide memory usage.
+type MemoryMetrics struct {
+	Time time.Time
+
+	// Physical memory, and memory in use, i.e. active, inactive, wired or compressed (in bytes).
+	Total uint64
+	Used  uint64
+}
+
+// Pressure returns the share of memory in use (0 - 100%).
+func (m *MemoryMetrics) Pressure() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+
+	return float64(m.Used) / float64(m.Total) * 100.0
+}
+
+// NetworkMetrics contains the network traffic of all interfaces during an interval.
+type NetworkMetrics struct {
+	Time time.Time
+
+	// Length of the interval ending with the sample (in s).
+	Interval float64
+
+	// Bytes and packets received and sent during the interval.
+	BytesIn    uint64
+	BytesOut   uint64
+	PacketsIn  uint64
+	PacketsOut uint64
+}
+
+// cpuMetricsFromSysmontap parses the CPU load of a sysmontap sample. It returns nil if the sample contains none.
+func cpuMetricsFromSysmontap(sample map[string]any, now time.Time) *CPUMetrics {
+	usage, ok := sample["SystemCPUUsage"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	cores := int(number(sample["EnabledCPUs"]))
+	if cores == 0 {
+		cores = int(number(sample["CPUCount"]))
+	}
+
+	return &CPUMetrics{
+		Time:       now,
+		Cores:      cores,
+		TotalLoad:  number(usage["CPU_TotalLoad"]),
+		UserLoad:   number(usage["CPU_UserLoad"]),
+		SystemLoad: number(usage["CPU_SystemLoad"]),
+	}
+}
+
+// systemFromSysmontap returns the system attributes of a sysmontap sample, and false if the sample contains none.
+func systemFromSysmontap(sample map[string]any) ([]float64, bool) {
+	attrs, ok := sample["System"].([]any)
+	if !ok || (len(attrs) < len(systemAttributes)) {
+		return nil, false
+	}
+
+	values := make([]float64, len(systemAttributes))
+	for i := range systemAttributes {
+		values[i] = number(attrs[i])
+	}
+
+	return values, true
+}
+
+// memoryMetricsFromSystem computes the memory usage from the system attributes of a sysmontap sample.
+func memoryMetricsFromSystem(system []float64, now time.Time) *MemoryMetrics {
+	free := system[0] + system[1] + system[2]
+	used := system[3] + system[4] + system[5] + system[6]
+	total := system[7]
+
+	if free+used <= 0 {
+		return nil
+	}
+
+	return &MemoryMetrics{
+		Time:  now,
+		Total: uint64(total),
+		Used:  uint64(total * used / (free + used)),
+	}
+}
+
+// networkMetricsFromSystem computes the network traffic since the previous sample from the cumulative counters in
+// the system attributes of a sysmontap sample. It returns nil for the first sample, or if the counters were reset.
+func networkMetricsFromSystem(system []float64, prev []float64, now time.Time, interval float64) *NetworkMetrics {
+	if prev == nil {
+		return nil
+	}
+
+	delta := func(i int) (uint64, bool) {
+		if system[i] < prev[i] {
+			return 0, false
+		}
+
+		return uint64(system[i] - prev[i]), true
+	}
+
+	bytesIn, ok1 := delta(8)
+	bytesOut, ok2 := delta(9)
+	packetsIn, ok3 := delta(10)
+	packetsOut, ok4 := delta(11)
+
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil
+	}
+
+	return &NetworkMetrics{
+		Time:       now,
+		Interval:   interval,
+		BytesIn:    bytesIn,
+		BytesOut:   bytesOut,
+		PacketsIn:  packetsIn,
+		PacketsOut: packetsOut,
+	}
+}
+
+// gpuAccumulator averages graphics samples over an interval.
+type gpuAccumulator struct {
+	sum GPUMetrics
+	n   int
+}
+
+// add adds a graphics sample.
+func (acc *gpuAccumulator) add(sample map[string]any) {
+	acc.sum.DeviceUtilization += number(sample["Device Utilization %"])
+	acc.sum.RendererUtilization += number(sample["Renderer Utilization %"])
+	acc.sum.TilerUtilization += number(sample["Tiler Utilization %"])
+	acc.sum.FramesPerSecond += number(sample["CoreAnimationFramesPerSecond"])
+	acc.n++
+}
+
+// flush returns the averages of all samples added since the last flush, or nil if there are none.
+func (acc *gpuAccumulator) flush(now time.Time) *GPUMetrics {
+	if acc.n == 0 {
+		return nil
+	}
+
+	n := float64(acc.n)
+
+	m := &GPUMetrics{
+		Time:                now,
+		DeviceUtilization:   acc.sum.DeviceUtilization / n,
+		RendererUtilization: acc.sum.RendererUtilization / n,
+		TilerUtilization:    acc.sum.TilerUtilization / n,
+		FramesPerSecond:     acc.sum.FramesPerSecond / n,
+	}
+
+	*acc = gpuAccumulator{}
+
+	return m
+}